@@ -0,0 +1,95 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+)
+
+type KVNamespace struct {
+	registry.ResourceBase
+
+	AccountID fields.StringInputField `state:"force_new"`
+	Title     fields.StringInputField
+
+	ID fields.StringOutputField
+
+	// Adopt allows to take over existing namespace with the same title.
+	Adopt bool `state:"-"`
+}
+
+func (o *KVNamespace) ReferenceID() string {
+	return fields.GenerateID("accounts/%s/storage/kv/namespaces/%s", o.AccountID, o.Title)
+}
+
+func (o *KVNamespace) GetName() string {
+	return fields.VerboseString(o.Title)
+}
+
+func (o *KVNamespace) Read(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+
+	namespaces, err := pctx.WranglerCloudflareClient().KVNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching kv namespaces: %w", err)
+	}
+
+	var found *config.KVNamespace
+
+	// Namespace is looked up by its ID once created, as it can be renamed, otherwise existing one with same title is used if adopted.
+	for _, ns := range namespaces {
+		if (o.ID.Current() != "" && ns.ID == o.ID.Current()) || (o.ID.Current() == "" && ns.Title == o.Title.Any()) {
+			found = ns
+
+			break
+		}
+	}
+
+	if found == nil {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	if o.ID.Current() == "" && !o.Adopt {
+		return fmt.Errorf("kv namespace '%s' already exists and is not managed by outblocks, set 'cloudflare.kv_adopt' property of app to take it over", found.Title)
+	}
+
+	o.MarkAsExisting()
+	o.ID.SetCurrent(found.ID)
+	o.Title.SetCurrent(found.Title)
+
+	return nil
+}
+
+func (o *KVNamespace) Create(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+
+	ns, err := pctx.WranglerCloudflareClient().CreateKVNamespace(ctx, o.Title.Wanted())
+	if err != nil {
+		return fmt.Errorf("error creating kv namespace '%s': %w", o.Title.Wanted(), err)
+	}
+
+	o.ID.SetCurrent(ns.ID)
+
+	return nil
+}
+
+func (o *KVNamespace) Update(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+
+	return pctx.WranglerCloudflareClient().RenameKVNamespace(ctx, o.ID.Current(), o.Title.Wanted())
+}
+
+func (o *KVNamespace) Delete(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+
+	if pctx.CloudflareClient().AccountID != o.AccountID.Current() {
+		return nil
+	}
+
+	return pctx.WranglerCloudflareClient().DeleteKVNamespace(ctx, o.ID.Current())
+}
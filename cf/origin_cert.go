@@ -2,12 +2,18 @@ package cf
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"reflect"
+	"time"
 
 	"github.com/cloudflare/cloudflare-go"
 	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
@@ -18,17 +24,28 @@ import (
 type OriginCertificate struct {
 	registry.ResourceBase
 
-	Hostnames       fields.ArrayInputField  `state:"force_new"`
-	RequestType     fields.StringInputField `state:"force_new" default:"origin-rsa"`
-	RequestValidity fields.IntInputField    `state:"force_new" default:"5475"`
+	Hostnames       fields.ArrayInputField
+	RequestType     fields.StringInputField `default:"origin-rsa"`
+	RequestValidity fields.IntInputField    `default:"5475"`
+	Valid           fields.BoolInputField   `default:"true"`
 
 	ID          fields.StringOutputField
 	ExpiresOn   fields.IntOutputField
 	CSR         fields.StringOutputField
 	Certificate fields.StringOutputField
 	PrivateKey  fields.StringOutputField
+
+	// RenewDays is how many days before expiry certificate gets replaced.
+	RenewDays int `state:"-"`
 }
 
+const (
+	DefaultOriginCertificateRenewDays = 30
+
+	OriginCertificateTypeRSA = "origin-rsa"
+	OriginCertificateTypeECC = "origin-ecc"
+)
+
 func (o *OriginCertificate) GetName() string {
 	h := o.Hostnames.Any()[0]
 
@@ -44,17 +61,97 @@ func (o *OriginCertificate) Read(ctx context.Context, meta interface{}) error {
 	}
 
 	c, err := cli.OriginCertificate(ctx, o.ID.Current())
-	if err != nil || !c.RevokedAt.IsZero() {
+	if err != nil {
+		var notFound *cloudflare.NotFoundError
+
+		if errors.As(err, &notFound) {
+			o.MarkAsNew()
+
+			return nil
+		}
+
+		return fmt.Errorf("error fetching origin certificate: %w", err)
+	}
+
+	if !c.RevokedAt.IsZero() {
 		o.MarkAsNew()
 
 		return nil
 	}
 
 	o.MarkAsExisting()
+	o.ExpiresOn.SetCurrent(int(c.ExpiresOn.Unix()))
+	o.Valid.SetCurrent(!o.needsRenewal(c.ExpiresOn))
 
 	return nil
 }
 
+// generateOriginCSR generates private key matching requested certificate type along with CSR signed by it.
+func generateOriginCSR(requestType string) (csrPem, keyPem string, err error) {
+	var (
+		key   crypto.Signer
+		block *pem.Block
+	)
+
+	switch requestType {
+	case OriginCertificateTypeRSA:
+		rsaKey, genErr := rsa.GenerateKey(rand.Reader, 2048)
+		if genErr != nil {
+			return "", "", fmt.Errorf("error generating rsa key: %w", genErr)
+		}
+
+		key = rsaKey
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}
+
+	case OriginCertificateTypeECC:
+		ecKey, genErr := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if genErr != nil {
+			return "", "", fmt.Errorf("error generating ecdsa key: %w", genErr)
+		}
+
+		keyBytes, err := x509.MarshalECPrivateKey(ecKey)
+		if err != nil {
+			return "", "", fmt.Errorf("error marshaling ecdsa key: %w", err)
+		}
+
+		key = ecKey
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}
+
+	default:
+		return "", "", fmt.Errorf("unsupported origin certificate request type: %s", requestType)
+	}
+
+	csr := x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName: "Cloudflare Origin Certificate",
+		},
+	}
+
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &csr, key)
+	if err != nil {
+		return "", "", fmt.Errorf("error creating certificate request: %w", err)
+	}
+
+	csrPem = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes}))
+	keyPem = string(pem.EncodeToMemory(block))
+
+	return csrPem, keyPem, nil
+}
+
+func (o *OriginCertificate) needsRenewal(expiresOn time.Time) bool {
+	renewDays := o.RenewDays
+	if renewDays == 0 {
+		renewDays = DefaultOriginCertificateRenewDays
+	}
+
+	// Renewal window cannot cover whole validity period, otherwise certificate would be renewed on every run.
+	if validity := o.RequestValidity.Any(); renewDays >= validity {
+		renewDays = validity / 3
+	}
+
+	return time.Until(expiresOn) < time.Duration(renewDays)*24*time.Hour
+}
+
 func (o *OriginCertificate) Create(ctx context.Context, meta interface{}) error {
 	pctx := meta.(*config.PluginContext)
 	cli := pctx.CloudflareClient()
@@ -65,21 +162,11 @@ func (o *OriginCertificate) Create(ctx context.Context, meta interface{}) error
 		hostnames[i] = v.(string)
 	}
 
-	keyBytes, _ := rsa.GenerateKey(rand.Reader, 2048)
-
-	csr := x509.CertificateRequest{
-		Subject: pkix.Name{
-			CommonName: "Cloudflare Origin Certificate",
-		},
+	certReqPem, privateKeyPem, err := generateOriginCSR(o.RequestType.Wanted())
+	if err != nil {
+		return err
 	}
 
-	csrBytes, _ := x509.CreateCertificateRequest(rand.Reader, &csr, keyBytes)
-	certReqPem := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes}))
-	privateKeyPem := string(pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(keyBytes),
-	}))
-
 	out, err := cli.CreateOriginCertificate(ctx, cloudflare.OriginCACertificate{
 		CSR:             certReqPem,
 		Hostnames:       hostnames,
@@ -97,16 +184,41 @@ func (o *OriginCertificate) Create(ctx context.Context, meta interface{}) error
 	}
 
 	o.ID.SetCurrent(cert.ID)
+	o.Hostnames.SetCurrent(o.Hostnames.Wanted())
+	o.RequestType.SetCurrent(o.RequestType.Wanted())
+	o.RequestValidity.SetCurrent(o.RequestValidity.Wanted())
 	o.CSR.SetCurrent(certReqPem)
 	o.PrivateKey.SetCurrent(privateKeyPem)
 	o.Certificate.SetCurrent(cert.Certificate)
 	o.ExpiresOn.SetCurrent(int(cert.ExpiresOn.Unix()))
+	o.Valid.SetCurrent(true)
 
 	return nil
 }
 
+// PendingReplacement returns true if existing certificate is going to be reissued.
+func (o *OriginCertificate) PendingReplacement() bool {
+	return !o.Valid.Current() ||
+		o.RequestType.Current() != o.RequestType.Wanted() ||
+		o.RequestValidity.Current() != o.RequestValidity.Wanted() ||
+		!reflect.DeepEqual(o.Hostnames.Current(), o.Hostnames.Wanted())
+}
+
+// Update reissues certificate. Old certificate is revoked at the end of deploy, after all plugins switched to the new one.
 func (o *OriginCertificate) Update(ctx context.Context, meta interface{}) error {
-	return fmt.Errorf("unimplemented")
+	pctx := meta.(*config.PluginContext)
+	oldID := o.ID.Current()
+
+	err := o.Create(ctx, meta)
+	if err != nil {
+		return err
+	}
+
+	if oldID != "" {
+		pctx.QueueOriginCertificateRevocation(oldID)
+	}
+
+	return nil
 }
 
 func (o *OriginCertificate) Delete(ctx context.Context, meta interface{}) error {
@@ -114,6 +226,15 @@ func (o *OriginCertificate) Delete(ctx context.Context, meta interface{}) error
 	cli := pctx.CloudflareClient()
 
 	_, err := cli.RevokeOriginCertificate(ctx, o.ID.Current())
+	if err != nil {
+		var notFound *cloudflare.NotFoundError
+
+		if errors.As(err, &notFound) {
+			return nil
+		}
 
-	return err
+		return fmt.Errorf("error revoking origin certificate: %w", err)
+	}
+
+	return nil
 }
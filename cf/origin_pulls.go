@@ -0,0 +1,386 @@
+package cf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+)
+
+const (
+	OriginPullsModeZone     = "zone"
+	OriginPullsModeHostname = "hostname"
+
+	// OriginPullsCAURL is where CA of certificate that cloudflare presents to origins by default is published.
+	OriginPullsCAURL = "https://developers.cloudflare.com/ssl/static/authenticated_origin_pull_ca.pem"
+
+	originPullsStatusOn = "on"
+)
+
+// OriginPulls enables Authenticated Origin Pulls for whole zone or for hostnames of zone.
+// Zone-level pulls use cloudflare certificate unless Certificate is set, per-hostname ones always require it.
+// Certificate is uploaded once and shared by all hostnames. Zone-level status that was set before is restored when pulls are no longer managed.
+type OriginPulls struct {
+	registry.ResourceBase
+
+	ZoneID      fields.StringInputField `state:"force_new"`
+	Zone        fields.StringInputField `state:"force_new"`
+	Mode        fields.StringInputField `state:"force_new"`
+	Hostnames   fields.ArrayInputField
+	Certificate fields.StringInputField
+	Enabled     fields.BoolInputField `default:"true"`
+
+	CertificateID  fields.StringOutputField
+	OriginalStatus fields.StringOutputField
+
+	// PrivateKey is only needed to upload certificate, so it is never stored in state.
+	PrivateKey string `state:"-"`
+}
+
+func (o *OriginPulls) ReferenceID() string {
+	return fields.GenerateID("zones/%s/origin_pulls/%s", o.Zone, o.Mode)
+}
+
+func (o *OriginPulls) GetName() string {
+	return fields.VerboseString(o.Zone)
+}
+
+func (o *OriginPulls) isPerHostname() bool {
+	return o.Mode.Any() == OriginPullsModeHostname
+}
+
+func (o *OriginPulls) Read(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+	zoneID := o.ZoneID.Any()
+
+	if zoneID == "" {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	if o.isPerHostname() {
+		return o.readHostnames(ctx, cli, zoneID)
+	}
+
+	return o.readZone(ctx, cli, zoneID)
+}
+
+func (o *OriginPulls) readZone(ctx context.Context, cli *cloudflare.API, zoneID string) error {
+	status, err := cli.GetAuthenticatedOriginPullsStatus(ctx, zoneID)
+	if err != nil {
+		return fmt.Errorf("error fetching authenticated origin pulls status: %w", err)
+	}
+
+	certificate := ""
+
+	if id := o.CertificateID.Current(); id != "" {
+		cert, err := cli.GetPerZoneAuthenticatedOriginPullsCertificateDetails(ctx, zoneID, id)
+		if err != nil {
+			var notFound *cloudflare.NotFoundError
+
+			if !errors.As(err, &notFound) {
+				return fmt.Errorf("error fetching origin pulls certificate: %w", err)
+			}
+
+			o.CertificateID.SetCurrent("")
+		} else {
+			certificate = cert.Certificate
+		}
+	}
+
+	o.MarkAsExisting()
+	o.ZoneID.SetCurrent(zoneID)
+	o.Zone.SetCurrent(o.Zone.Any())
+	o.Mode.SetCurrent(OriginPullsModeZone)
+	o.Hostnames.SetCurrent([]interface{}{})
+	o.Enabled.SetCurrent(status.Value == originPullsStatusOn)
+	o.Certificate.SetCurrent(certificate)
+
+	if o.OriginalStatus.Current() == "" {
+		o.OriginalStatus.SetCurrent(status.Value)
+	}
+
+	return nil
+}
+
+func (o *OriginPulls) readHostnames(ctx context.Context, cli *cloudflare.API, zoneID string) error {
+	certID := o.CertificateID.Current()
+	certificate := ""
+	enabled := false
+
+	var hostnames []string
+
+	// Check both hostnames in state and wanted ones, so that removed hostnames get disabled.
+	lookup := make(map[string]struct{})
+
+	for _, h := range append(o.Hostnames.Current(), o.Hostnames.Wanted()...) {
+		lookup[h.(string)] = struct{}{}
+	}
+
+	for hostname := range lookup {
+		cfg, err := cli.GetPerHostnameAuthenticatedOriginPullsConfig(ctx, zoneID, hostname)
+		if err != nil {
+			var notFound *cloudflare.NotFoundError
+
+			if errors.As(err, &notFound) {
+				continue
+			}
+
+			return fmt.Errorf("error fetching authenticated origin pulls of '%s': %w", hostname, err)
+		}
+
+		if cfg.CertID == "" || (certID != "" && cfg.CertID != certID) {
+			continue
+		}
+
+		certID = cfg.CertID
+		certificate = cfg.Certificate
+		enabled = enabled || cfg.Enabled
+
+		hostnames = append(hostnames, hostname)
+	}
+
+	if certID == "" {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	sort.Strings(hostnames)
+
+	current := make([]interface{}, len(hostnames))
+
+	for i, h := range hostnames {
+		current[i] = h
+	}
+
+	o.MarkAsExisting()
+	o.ZoneID.SetCurrent(zoneID)
+	o.Zone.SetCurrent(o.Zone.Any())
+	o.Mode.SetCurrent(OriginPullsModeHostname)
+	o.Hostnames.SetCurrent(current)
+	o.Enabled.SetCurrent(enabled)
+	o.CertificateID.SetCurrent(certID)
+	o.Certificate.SetCurrent(certificate)
+
+	return nil
+}
+
+func (o *OriginPulls) uploadCertificate(ctx context.Context, cli *cloudflare.API) error {
+	zoneID := o.ZoneID.Wanted()
+	cert := o.Certificate.Wanted()
+
+	if cert == "" {
+		o.CertificateID.SetCurrent("")
+
+		return nil
+	}
+
+	if o.PrivateKey == "" {
+		return fmt.Errorf("authenticated origin pulls certificate of zone '%s' requires private key to be uploaded", o.Zone.Wanted())
+	}
+
+	if o.Mode.Wanted() == OriginPullsModeHostname {
+		res, err := cli.UploadPerHostnameAuthenticatedOriginPullsCertificate(ctx, zoneID, cloudflare.PerHostnameAuthenticatedOriginPullsCertificateParams{
+			Certificate: cert,
+			PrivateKey:  o.PrivateKey,
+		})
+		if err != nil {
+			return fmt.Errorf("error uploading origin pulls certificate: %w", err)
+		}
+
+		o.CertificateID.SetCurrent(res.ID)
+
+		return nil
+	}
+
+	res, err := cli.UploadPerZoneAuthenticatedOriginPullsCertificate(ctx, zoneID, cloudflare.PerZoneAuthenticatedOriginPullsCertificateParams{
+		Certificate: cert,
+		PrivateKey:  o.PrivateKey,
+	})
+	if err != nil {
+		return fmt.Errorf("error uploading origin pulls certificate: %w", err)
+	}
+
+	o.CertificateID.SetCurrent(res.ID)
+
+	return nil
+}
+
+func deleteOriginPullsCertificate(ctx context.Context, cli *cloudflare.API, zoneID, mode, id string) error {
+	var err error
+
+	if id == "" {
+		return nil
+	}
+
+	if mode == OriginPullsModeHostname {
+		_, err = cli.DeletePerHostnameAuthenticatedOriginPullsCertificate(ctx, zoneID, id)
+	} else {
+		_, err = cli.DeletePerZoneAuthenticatedOriginPullsCertificate(ctx, zoneID, id)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error deleting origin pulls certificate: %w", err)
+	}
+
+	return nil
+}
+
+func setHostnamesOriginPulls(ctx context.Context, cli *cloudflare.API, zoneID, certID string, hostnames []string, enabled bool) error {
+	if len(hostnames) == 0 {
+		return nil
+	}
+
+	cfg := make([]cloudflare.PerHostnameAuthenticatedOriginPullsConfig, len(hostnames))
+
+	for i, h := range hostnames {
+		cfg[i] = cloudflare.PerHostnameAuthenticatedOriginPullsConfig{
+			Hostname: h,
+			CertID:   certID,
+			Enabled:  enabled,
+		}
+	}
+
+	_, err := cli.EditPerHostnameAuthenticatedOriginPullsConfig(ctx, zoneID, cfg)
+	if err != nil {
+		return fmt.Errorf("error updating authenticated origin pulls: %w", err)
+	}
+
+	return nil
+}
+
+func setZoneOriginPulls(ctx context.Context, cli *cloudflare.API, zoneID string, enabled bool) error {
+	_, err := cli.SetAuthenticatedOriginPullsStatus(ctx, zoneID, enabled)
+	if err != nil {
+		return fmt.Errorf("error updating authenticated origin pulls: %w", err)
+	}
+
+	return nil
+}
+
+func stringsFromArray(arr []interface{}) []string {
+	ret := make([]string, len(arr))
+
+	for i, v := range arr {
+		ret[i] = v.(string)
+	}
+
+	return ret
+}
+
+// removedHostnames returns hostnames of current array that are not in wanted one.
+func removedHostnames(current, wanted []interface{}) []string {
+	m := make(map[string]struct{}, len(wanted))
+
+	for _, h := range wanted {
+		m[h.(string)] = struct{}{}
+	}
+
+	var ret []string
+
+	for _, h := range current {
+		if _, ok := m[h.(string)]; !ok {
+			ret = append(ret, h.(string))
+		}
+	}
+
+	return ret
+}
+
+func (o *OriginPulls) Create(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+
+	if o.Mode.Wanted() == OriginPullsModeHostname && o.Certificate.Wanted() == "" {
+		return fmt.Errorf("per-hostname authenticated origin pulls of zone '%s' require certificate", o.Zone.Wanted())
+	}
+
+	if o.Mode.Wanted() == OriginPullsModeZone {
+		status, err := cli.GetAuthenticatedOriginPullsStatus(ctx, o.ZoneID.Wanted())
+		if err != nil {
+			return fmt.Errorf("error fetching authenticated origin pulls status: %w", err)
+		}
+
+		o.OriginalStatus.SetCurrent(status.Value)
+	}
+
+	err := o.uploadCertificate(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	return o.apply(ctx, cli)
+}
+
+func (o *OriginPulls) apply(ctx context.Context, cli *cloudflare.API) error {
+	zoneID := o.ZoneID.Wanted()
+
+	if o.Mode.Wanted() == OriginPullsModeHostname {
+		return setHostnamesOriginPulls(ctx, cli, zoneID, o.CertificateID.Current(), stringsFromArray(o.Hostnames.Wanted()), o.Enabled.Wanted())
+	}
+
+	return setZoneOriginPulls(ctx, cli, zoneID, o.Enabled.Wanted())
+}
+
+func (o *OriginPulls) Update(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+	zoneID := o.ZoneID.Wanted()
+	oldID := o.CertificateID.Current()
+
+	certChanged := o.Certificate.Current() != o.Certificate.Wanted()
+
+	if certChanged {
+		err := o.uploadCertificate(ctx, cli)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := o.apply(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	err = setHostnamesOriginPulls(ctx, cli, zoneID, o.CertificateID.Current(), removedHostnames(o.Hostnames.Current(), o.Hostnames.Wanted()), false)
+	if err != nil {
+		return err
+	}
+
+	// Old certificate is removed only after the new one is in use.
+	if certChanged {
+		return deleteOriginPullsCertificate(ctx, cli, zoneID, o.Mode.Wanted(), oldID)
+	}
+
+	return nil
+}
+
+func (o *OriginPulls) Delete(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+	zoneID := o.ZoneID.Current()
+	mode := o.Mode.Current()
+	certID := o.CertificateID.Current()
+
+	var err error
+
+	if mode == OriginPullsModeHostname {
+		err = setHostnamesOriginPulls(ctx, cli, zoneID, certID, stringsFromArray(o.Hostnames.Current()), false)
+	} else if o.OriginalStatus.Current() != originPullsStatusOn {
+		err = setZoneOriginPulls(ctx, cli, zoneID, false)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return deleteOriginPullsCertificate(ctx, cli, zoneID, mode, certID)
+}
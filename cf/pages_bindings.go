@@ -0,0 +1,159 @@
+package cf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/util"
+)
+
+const (
+	PagesBindingKV            = "kv_namespaces"
+	PagesBindingR2            = "r2_buckets"
+	PagesBindingD1            = "d1_databases"
+	PagesBindingDurableObject = "durable_object_namespaces"
+
+	PagesEnvVarPlainText  = "plain_text"
+	PagesEnvVarSecretText = "secret_text"
+)
+
+// PagesBindingKey returns key of binding used in bindings map field.
+func PagesBindingKey(typ, name string) string {
+	return fmt.Sprintf("%s:%s", typ, name)
+}
+
+func pagesBindingsOfConfig(cfg *config.PagesProjectDeploymentConfig) map[string]map[string]*config.PagesProjectBinding {
+	return map[string]map[string]*config.PagesProjectBinding{
+		PagesBindingKV:            cfg.KVNamespaces,
+		PagesBindingR2:            cfg.R2Buckets,
+		PagesBindingD1:            cfg.D1Databases,
+		PagesBindingDurableObject: cfg.DurableObjectNamespaces,
+	}
+}
+
+func pagesBindingID(typ string, b *config.PagesProjectBinding) string {
+	switch typ {
+	case PagesBindingR2:
+		return b.Name
+	case PagesBindingD1:
+		return b.ID
+	default:
+		return b.NamespaceID
+	}
+}
+
+func newPagesBinding(typ, id string) *config.PagesProjectBinding {
+	switch typ {
+	case PagesBindingR2:
+		return &config.PagesProjectBinding{Name: id}
+	case PagesBindingD1:
+		return &config.PagesProjectBinding{ID: id}
+	default:
+		return &config.PagesProjectBinding{NamespaceID: id}
+	}
+}
+
+func pagesBindingsToMap(cfg *config.PagesProjectDeploymentConfig) map[string]interface{} {
+	ret := make(map[string]interface{})
+
+	for typ, bindings := range pagesBindingsOfConfig(cfg) {
+		for name, b := range bindings {
+			if b != nil {
+				ret[PagesBindingKey(typ, name)] = pagesBindingID(typ, b)
+			}
+		}
+	}
+
+	return ret
+}
+
+// pagesBindingsPatch returns bindings grouped by type, along with nil values for those that are no longer wanted.
+func pagesBindingsPatch(current, wanted map[string]interface{}) map[string]map[string]*config.PagesProjectBinding {
+	ret := make(map[string]map[string]*config.PagesProjectBinding)
+
+	set := func(key string, b func(typ string) *config.PagesProjectBinding) {
+		typ, name, ok := strings.Cut(key, ":")
+		if !ok {
+			return
+		}
+
+		if ret[typ] == nil {
+			ret[typ] = make(map[string]*config.PagesProjectBinding)
+		}
+
+		ret[typ][name] = b(typ)
+	}
+
+	for k := range current {
+		set(k, func(string) *config.PagesProjectBinding { return nil })
+	}
+
+	for k, v := range wanted {
+		id := v.(string)
+
+		set(k, func(typ string) *config.PagesProjectBinding { return newPagesBinding(typ, id) })
+	}
+
+	return ret
+}
+
+func pagesEnvVarsToMap(vars map[string]*config.PagesProjectEnvVar) map[string]interface{} {
+	ret := make(map[string]interface{}, len(vars))
+
+	for k, v := range vars {
+		if v != nil && v.Type != PagesEnvVarSecretText {
+			ret[k] = v.Value
+		}
+	}
+
+	return ret
+}
+
+// pagesSecretsToMap returns secrets set in project, values are never returned by API so known ones are kept as is.
+func pagesSecretsToMap(vars map[string]*config.PagesProjectEnvVar, known map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{})
+
+	for k, v := range vars {
+		if v == nil || v.Type != PagesEnvVarSecretText {
+			continue
+		}
+
+		ret[k] = ""
+
+		if val, ok := known[k]; ok {
+			ret[k] = val
+		}
+	}
+
+	return ret
+}
+
+// PagesSecretHash returns hash of secret that is stored in state instead of its value.
+func PagesSecretHash(name, value string) string {
+	return util.SHAString(name + "\x00" + value)
+}
+
+// pagesEnvVarsPatch returns env vars and secrets to set, along with nil values for those that are no longer wanted.
+// Secrets are tracked by their hashes, so their actual values have to be passed separately.
+func pagesEnvVarsPatch(currentVars, currentSecrets, wantedVars map[string]interface{}, wantedSecrets map[string]string) map[string]*config.PagesProjectEnvVar {
+	ret := make(map[string]*config.PagesProjectEnvVar, len(wantedVars)+len(wantedSecrets))
+
+	for k := range currentVars {
+		ret[k] = nil
+	}
+
+	for k := range currentSecrets {
+		ret[k] = nil
+	}
+
+	for k, v := range wantedVars {
+		ret[k] = &config.PagesProjectEnvVar{Type: PagesEnvVarPlainText, Value: v.(string)}
+	}
+
+	for k, v := range wantedSecrets {
+		ret[k] = &config.PagesProjectEnvVar{Type: PagesEnvVarSecretText, Value: v}
+	}
+
+	return ret
+}
@@ -2,7 +2,9 @@ package cf
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
 	"github.com/outblocks/outblocks-plugin-go/registry"
@@ -16,6 +18,28 @@ type PagesDeployment struct {
 	ProjectName fields.StringInputField `state:"force_new"`
 	AccountID   fields.StringInputField `state:"force_new"`
 	Manifest    fields.MapInputField    `state:"force_new"`
+	Headers     fields.StringInputField `state:"force_new"`
+	Redirects   fields.StringInputField `state:"force_new"`
+	WorkerHash  fields.StringInputField `state:"force_new"`
+	Routes      fields.StringInputField `state:"force_new"`
+	// Branch of preview deployment, production deployment is made when empty.
+	Branch fields.StringInputField `state:"force_new"`
+	// PinnedID is previous deployment that should be live instead of the deployed one, e.g. to roll back a bad release.
+	PinnedID fields.StringInputField
+	Live     fields.BoolInputField `default:"true"`
+
+	// WorkerPath is compiled Pages Functions worker.
+	WorkerPath string `state:"-"`
+	// FunctionsDir is compiled into worker in FunctionsOutDir when deployment is created, unless WorkerPath is set.
+	FunctionsDir    string `state:"-"`
+	FunctionsOutDir string `state:"-"`
+	// DriftedLiveID is deployment made live outside of outblocks, which is only reported unless deployment is pinned.
+	DriftedLiveID string `state:"-"`
+
+	ID          fields.StringOutputField
+	URL         fields.StringOutputField
+	StageStatus fields.StringOutputField
+	LiveID      fields.StringOutputField
 }
 
 func (o *PagesDeployment) ReferenceID() string {
@@ -26,7 +50,89 @@ func (o *PagesDeployment) GetName() string {
 	return o.Name
 }
 
+func (o *PagesDeployment) setDeployment(d *config.PagesDeploymentDetails) {
+	o.URL.SetCurrent(d.URL)
+	o.StageStatus.SetCurrent(fmt.Sprintf("%s: %s", d.LatestStage.Name, d.LatestStage.Status))
+}
+
 func (o *PagesDeployment) Read(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	wranglerCli := pctx.WranglerCloudflareClient()
+
+	// Deployments made before they were tracked cannot be verified.
+	if o.ID.Current() == "" || o.LiveID.Current() == "" {
+		return nil
+	}
+
+	proj, err := wranglerCli.PagesProject(ctx, o.ProjectName.Any())
+	if err != nil {
+		return fmt.Errorf("error fetching pages project: %w", err)
+	}
+
+	live := proj.CanonicalDeployment
+	drifted := live == nil || live.ID != o.LiveID.Current()
+
+	// Live deployment changed outside of outblocks (e.g. rolled back in dashboard), it is only enforced when pinned.
+	if drifted && live != nil && o.PinnedID.Wanted() == "" {
+		o.DriftedLiveID = live.ID
+		drifted = false
+	}
+
+	o.Live.SetCurrent(!drifted)
+
+	return nil
+}
+
+// liveTarget returns deployment that should be live.
+func (o *PagesDeployment) liveTarget() string {
+	if id := o.PinnedID.Wanted(); id != "" {
+		return id
+	}
+
+	return o.ID.Current()
+}
+
+func (o *PagesDeployment) makeLive(ctx context.Context, wranglerCli *config.WranglerCloudflareAPI) error {
+	target := o.liveTarget()
+
+	if target == "" || o.Branch.Wanted() != "" || (target == o.LiveID.Current() && o.Live.Current()) {
+		return nil
+	}
+
+	d, err := wranglerCli.RollbackPagesDeployment(ctx, o.ProjectName.Wanted(), target)
+	if err != nil {
+		return fmt.Errorf("error rolling back pages project '%s' to deployment '%s': %w", o.ProjectName.Wanted(), target, err)
+	}
+
+	o.setDeployment(d)
+	o.LiveID.SetCurrent(target)
+	o.Live.SetCurrent(true)
+
+	return nil
+}
+
+// addGeneratedPagesRoutes adds routes generated along with functions worker, unless routes were already set explicitly.
+func addGeneratedPagesRoutes(files map[string]string, routesFile string) error {
+	if _, ok := files[PagesRoutesFile]; ok {
+		return nil
+	}
+
+	data, err := os.ReadFile(routesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = ValidatePagesRoutes(string(data))
+	if err != nil {
+		return fmt.Errorf("generated %s is invalid: %w", PagesRoutesFile, err)
+	}
+
+	files[PagesRoutesFile] = string(data)
+
 	return nil
 }
 
@@ -41,13 +147,74 @@ func (o *PagesDeployment) Create(ctx context.Context, meta interface{}) error {
 		manifestStr[k] = v.(string)
 	}
 
-	err := wranglerCli.CreatePagesDeployment(ctx, o.ProjectName.Wanted(), manifestStr)
+	files := make(map[string]string)
+
+	if v := o.Headers.Wanted(); v != "" {
+		files[PagesHeadersFile] = v
+	}
+
+	if v := o.Redirects.Wanted(); v != "" {
+		files[PagesRedirectsFile] = v
+	}
+
+	if v := o.Routes.Wanted(); v != "" {
+		files[PagesRoutesFile] = v
+	}
+
+	workerPath := o.WorkerPath
+
+	if workerPath == "" && o.FunctionsDir != "" {
+		var (
+			routesFile string
+			err        error
+		)
 
-	return err
+		workerPath, routesFile, err = BuildPagesFunctions(ctx, o.FunctionsDir, o.FunctionsOutDir)
+		if err != nil {
+			return err
+		}
+
+		err = addGeneratedPagesRoutes(files, routesFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if workerPath != "" {
+		worker, err := os.ReadFile(workerPath)
+		if err != nil {
+			return err
+		}
+
+		files[PagesWorkerFile] = string(worker)
+	}
+
+	d, err := wranglerCli.CreatePagesDeployment(ctx, o.ProjectName.Wanted(), o.Branch.Wanted(), manifestStr, files)
+	if err != nil {
+		return err
+	}
+
+	o.ID.SetCurrent(d.ID)
+	o.setDeployment(d)
+
+	// Preview deployments do not affect what is live.
+	if o.Branch.Wanted() != "" {
+		return nil
+	}
+
+	o.LiveID.SetCurrent(d.ID)
+	o.Live.SetCurrent(true)
+
+	return o.makeLive(ctx, wranglerCli)
 }
 
 func (o *PagesDeployment) Update(ctx context.Context, meta interface{}) error {
-	return fmt.Errorf("unimplemented")
+	pctx := meta.(*config.PluginContext)
+
+	// Force rollback as live deployment is either out of sync or pin changed.
+	o.Live.SetCurrent(false)
+
+	return o.makeLive(ctx, pctx.WranglerCloudflareClient())
 }
 
 func (o *PagesDeployment) Delete(ctx context.Context, meta interface{}) error {
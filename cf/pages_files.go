@@ -1,16 +1,25 @@
 package cf
 
 import (
+	"bufio"
 	"context"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"mime"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
 	"github.com/outblocks/outblocks-plugin-go/registry"
 	"github.com/outblocks/outblocks-plugin-go/registry/fields"
 	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
@@ -24,8 +33,21 @@ const (
 	UploadConcurrency           = 3
 	UploadBucketMaxFiles        = 5000
 	UploadBucketMaxSize         = 50 * 1024 * 1024
+	UploadMaxRetries            = 5
+	UploadRetryMinDelay         = time.Second
+	UploadRetryMaxDelay         = 30 * time.Second
+	UploadTimeout               = 5 * time.Minute
+	UploadMaxSplitDepth         = 4
 )
 
+// PagesSpecialFiles are files of build dir that are never uploaded as assets.
+var PagesSpecialFiles = map[string]bool{
+	PagesHeadersFile:   true,
+	PagesRedirectsFile: true,
+	PagesRoutesFile:    true,
+	PagesWorkerFile:    true,
+}
+
 type PagesFiles struct {
 	registry.ResourceBase
 
@@ -90,7 +112,17 @@ func (o *PagesFiles) Process(ctx context.Context, meta interface{}) error {
 		uploadFiles[i] = o.Hashes[h]
 	}
 
-	err := pagesUpload(ctx, cli, uploadFiles)
+	err := pagesUpload(ctx, cli, uploadFiles, func(files, totalFiles int, size, totalSize int64) {
+		pctx.ReportApplyProgress(&apiv1.ApplyAction{
+			Type:       apiv1.ApplyAction_TYPE_PROCESS,
+			Namespace:  pagesProject,
+			ObjectId:   "pages_files",
+			ObjectType: "Cloudflare Pages Files",
+			ObjectName: fmt.Sprintf("%s: uploaded %d/%d files (%.1f/%.1f MB)", o.Name, files, totalFiles, float64(size)/1024/1024, float64(totalSize)/1024/1024),
+			Progress:   int32(files),
+			Total:      int32(totalFiles),
+		})
+	})
 	if err != nil {
 		return fmt.Errorf("error uploading cloudflare pages files: %w", err)
 	}
@@ -108,29 +140,201 @@ type uploadBucket struct {
 	remainingSize int64
 }
 
-func (b *uploadBucket) payload() ([]map[string]interface{}, error) {
-	ret := make([]map[string]interface{}, len(b.files))
+func (b *uploadBucket) size() int64 {
+	var ret int64
+
+	for _, f := range b.files {
+		ret += f.Size
+	}
+
+	return ret
+}
+
+func (b *uploadBucket) split() (left, right *uploadBucket) {
+	half := len(b.files) / 2
+
+	return &uploadBucket{files: b.files[:half]}, &uploadBucket{files: b.files[half:]}
+}
+
+// writePayload streams bucket files as JSON upload payload, reading and base64 encoding them on the fly.
+func (b *uploadBucket) writePayload(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	_, err := bw.WriteString("[")
+	if err != nil {
+		return err
+	}
 
 	for i, f := range b.files {
-		bytes, err := os.ReadFile(f.Path)
+		if i > 0 {
+			_, err = bw.WriteString(",")
+			if err != nil {
+				return err
+			}
+		}
+
+		err = writeUploadFile(bw, f)
 		if err != nil {
-			return nil, err
+			return err
 		}
+	}
+
+	_, err = bw.WriteString("]")
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func writeUploadFile(w *bufio.Writer, f *PagesFileInfo) error {
+	key, err := json.Marshal(f.Hash)
+	if err != nil {
+		return err
+	}
+
+	contentType, err := json.Marshal(f.ContentType)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = fmt.Fprintf(w, `{"key":%s,"value":"`, key)
+	if err != nil {
+		return err
+	}
+
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+
+	_, err = io.Copy(enc, file)
+	if err != nil {
+		return err
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, `","metadata":{"contentType":%s},"base64":true}`, contentType)
+
+	return err
+}
+
+type pagesUploadProgress struct {
+	files, totalFiles int
+	size, totalSize   int64
+	report            func(files, totalFiles int, size, totalSize int64)
+	mu                sync.Mutex
+}
+
+func (p *pagesUploadProgress) add(b *uploadBucket) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.files += len(b.files)
+	p.size += b.size()
 
-		ret[i] = map[string]interface{}{
-			"key":   f.Hash,
-			"value": base64.StdEncoding.EncodeToString(bytes),
-			"metadata": map[string]string{
-				"contentType": f.ContentType,
-			},
-			"base64": true,
+	if p.report != nil {
+		p.report(p.files, p.totalFiles, p.size, p.totalSize)
+	}
+}
+
+func isRetryableUploadError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var uploadErr *config.PagesUploadError
+	if errors.As(err, &uploadErr) {
+		return uploadErr.Retryable()
+	}
+
+	// Network errors and timeouts.
+	return true
+}
+
+// pagesBucketUploader sends single upload request with payload streamed by writePayload.
+type pagesBucketUploader func(ctx context.Context, writePayload func(w io.Writer) error) error
+
+// uploadBucketWithRetries uploads bucket, retrying on transient errors. Size related errors are not retried when bucket can be split instead.
+func uploadBucketWithRetries(ctx context.Context, upload pagesBucketUploader, b *uploadBucket, splittable bool) error {
+	var err error
+
+	for i := 0; i <= UploadMaxRetries; i++ {
+		if i > 0 {
+			delay := UploadRetryMinDelay << (i - 1)
+			if delay > UploadRetryMaxDelay {
+				delay = UploadRetryMaxDelay
+			}
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return err
+			}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, UploadTimeout)
+		err = upload(attemptCtx, b.writePayload)
+
+		cancel()
+
+		if err == nil || !isRetryableUploadError(ctx, err) || (splittable && isSplittableUploadError(ctx, err)) {
+			return err
 		}
 	}
 
-	return ret, nil
+	return err
+}
+
+// isSplittableUploadError returns true if bucket upload failed because of its size, i.e. it was rejected as too large or timed out.
+func isSplittableUploadError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var uploadErr *config.PagesUploadError
+	if errors.As(err, &uploadErr) {
+		return uploadErr.TooLarge()
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// uploadBucketSplitting uploads bucket, splitting it in halves (up to UploadMaxSplitDepth times) if it is too large to be uploaded.
+// Other errors are returned as is once retries are exhausted.
+func uploadBucketSplitting(ctx context.Context, upload pagesBucketUploader, b *uploadBucket, progress *pagesUploadProgress, depth int) error {
+	splittable := len(b.files) > 1 && depth < UploadMaxSplitDepth
+
+	err := uploadBucketWithRetries(ctx, upload, b, splittable)
+	if err == nil {
+		progress.add(b)
+
+		return nil
+	}
+
+	if !splittable || !isSplittableUploadError(ctx, err) {
+		return err
+	}
+
+	left, right := b.split()
+
+	err = uploadBucketSplitting(ctx, upload, left, progress, depth+1)
+	if err != nil {
+		return err
+	}
+
+	return uploadBucketSplitting(ctx, upload, right, progress, depth+1)
 }
 
-func pagesUpload(ctx context.Context, cli *config.WranglerCloudflarePagesAPI, files []*PagesFileInfo) error {
+func pagesUpload(ctx context.Context, cli *config.WranglerCloudflarePagesAPI, files []*PagesFileInfo, report func(files, totalFiles int, size, totalSize int64)) error {
 	sort.Slice(files, func(i int, j int) bool {
 		return files[i].Size < files[j].Size
 	})
@@ -177,6 +381,15 @@ func pagesUpload(ctx context.Context, cli *config.WranglerCloudflarePagesAPI, fi
 		bucketOffset++
 	}
 
+	progress := &pagesUploadProgress{
+		totalFiles: len(files),
+		report:     report,
+	}
+
+	for _, f := range files {
+		progress.totalSize += f.Size
+	}
+
 	g, _ := errgroup.WithConcurrency(ctx, UploadConcurrency)
 
 	for _, b := range buckets {
@@ -187,12 +400,7 @@ func pagesUpload(ctx context.Context, cli *config.WranglerCloudflarePagesAPI, fi
 		}
 
 		g.Go(func() error {
-			payload, err := b.payload()
-			if err != nil {
-				return err
-			}
-
-			return cli.UploadBucket(ctx, payload)
+			return uploadBucketSplitting(ctx, cli.UploadBucket, b, progress, 0)
 		})
 	}
 
@@ -207,6 +415,7 @@ type PagesFileInfo struct {
 	ContentType string
 }
 
+// PagesHashFile returns hash of file used as its key, PagesHashVersion has to be bumped when it changes.
 func PagesHashFile(f string) (string, error) {
 	bytes, err := os.ReadFile(f)
 	if err != nil {
@@ -224,21 +433,25 @@ func PagesHashFile(f string) (string, error) {
 	return hex.EncodeToString(sum[:])[:32], nil
 }
 
-func PagesFindFiles(root string, patterns []string) (ret map[string]*PagesFileInfo, err error) {
-	ret = make(map[string]*PagesFileInfo)
+// PagesFindFiles finds and hashes files to upload, using cache (if not nil) to skip hashing of unchanged files.
+func PagesFindFiles(ctx context.Context, root string, patterns []string, cache *PagesHashCache) (map[string]*PagesFileInfo, error) {
+	var (
+		files []*PagesFileInfo
+		infos []os.FileInfo
+	)
 
-	err = plugin_util.WalkWithExclusions(root, patterns, func(path, rel string, info os.FileInfo) error {
+	err := plugin_util.WalkWithExclusions(root, patterns, func(path, rel string, info os.FileInfo) error {
 		if info.IsDir() {
 			return nil
 		}
 
-		if info.Size() > CloudflarePagesMaxSize {
-			return fmt.Errorf("CloudFlare Pages only supports files up to %d bytes in size\nfile: %s size: %d", CloudflarePagesMaxFileCount, path, info.Size())
+		// Special files are sent along with deployment instead of being served as assets.
+		if PagesSpecialFiles[rel] || strings.HasPrefix(filepath.ToSlash(rel), PagesFunctionsDir+"/") {
+			return nil
 		}
 
-		hash, err := PagesHashFile(path)
-		if err != nil {
-			return err
+		if info.Size() > CloudflarePagesMaxSize {
+			return fmt.Errorf("CloudFlare Pages only supports files up to %d bytes in size\nfile: %s size: %d", CloudflarePagesMaxSize, path, info.Size())
 		}
 
 		contentType := mime.TypeByExtension(filepath.Ext(path))
@@ -246,16 +459,55 @@ func PagesFindFiles(root string, patterns []string) (ret map[string]*PagesFileIn
 			contentType = "application/octet-stream"
 		}
 
-		ret[hash] = &PagesFileInfo{
-			Hash:        hash,
+		files = append(files, &PagesFileInfo{
 			Path:        path,
 			Rel:         rel,
 			Size:        info.Size(),
 			ContentType: contentType,
-		}
+		})
+		infos = append(infos, info)
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	g, _ := errgroup.WithConcurrency(ctx, runtime.NumCPU())
+
+	for i, f := range files {
+		f := f
+		info := infos[i]
+
+		g.Go(func() error {
+			if hash, ok := cache.Get(f.Path, info); ok {
+				f.Hash = hash
+
+				return nil
+			}
 
-	return ret, err
+			hash, err := PagesHashFile(f.Path)
+			if err != nil {
+				return err
+			}
+
+			f.Hash = hash
+			cache.Set(f.Path, info, hash)
+
+			return nil
+		})
+	}
+
+	err = g.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]*PagesFileInfo, len(files))
+
+	for _, f := range files {
+		ret[f.Hash] = f
+	}
+
+	return ret, nil
 }
@@ -0,0 +1,164 @@
+package cf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
+)
+
+func TestIsSplittableUploadError(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		desc string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"too large", context.Background(), &config.PagesUploadError{StatusCode: http.StatusRequestEntityTooLarge}, true},
+		{"wrapped too large", context.Background(), fmt.Errorf("upload: %w", &config.PagesUploadError{StatusCode: http.StatusRequestEntityTooLarge}), true},
+		{"timeout", context.Background(), fmt.Errorf("upload: %w", context.DeadlineExceeded), true},
+		{"server error", context.Background(), &config.PagesUploadError{StatusCode: http.StatusInternalServerError}, false},
+		{"bad request", context.Background(), &config.PagesUploadError{StatusCode: http.StatusBadRequest}, false},
+		{"network error", context.Background(), errors.New("connection reset"), false},
+		{"cancelled", cancelled, context.DeadlineExceeded, false},
+	}
+
+	for _, tt := range tests {
+		if got := isSplittableUploadError(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("%s: isSplittableUploadError() = %v, want %v", tt.desc, got, tt.want)
+		}
+	}
+}
+
+// testBucketUploader accepts buckets of up to maxFiles files, failing bigger ones with err.
+type testBucketUploader struct {
+	maxFiles int
+	err      error
+
+	calls    int
+	uploaded []string
+	mu       sync.Mutex
+}
+
+func (u *testBucketUploader) upload(_ context.Context, writePayload func(w io.Writer) error) error {
+	var buf bytes.Buffer
+
+	err := writePayload(&buf)
+	if err != nil {
+		return err
+	}
+
+	var payload []struct {
+		Key string `json:"key"`
+	}
+
+	err = json.Unmarshal(buf.Bytes(), &payload)
+	if err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.calls++
+
+	if len(payload) > u.maxFiles {
+		return u.err
+	}
+
+	for _, f := range payload {
+		u.uploaded = append(u.uploaded, f.Key)
+	}
+
+	return nil
+}
+
+func testUploadBucket(t *testing.T, count int) *uploadBucket {
+	t.Helper()
+
+	dir := t.TempDir()
+	b := &uploadBucket{}
+
+	for i := 0; i < count; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d.txt", i))
+
+		err := os.WriteFile(path, []byte(fmt.Sprintf("file %d", i)), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b.files = append(b.files, &PagesFileInfo{
+			Path:        path,
+			Hash:        fmt.Sprintf("hash-%02d", i),
+			Size:        1,
+			ContentType: "text/plain",
+		})
+	}
+
+	return b
+}
+
+func TestUploadBucketSplitting(t *testing.T) {
+	tooLarge := &config.PagesUploadError{StatusCode: http.StatusRequestEntityTooLarge}
+
+	tests := []struct {
+		desc      string
+		files     int
+		maxFiles  int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{desc: "fits", files: 8, maxFiles: 8, err: tooLarge, wantCalls: 1},
+		{desc: "split once", files: 8, maxFiles: 4, err: tooLarge, wantCalls: 3},
+		{desc: "split uneven", files: 5, maxFiles: 2, err: tooLarge, wantCalls: 5},
+		{desc: "split on timeout", files: 4, maxFiles: 2, err: context.DeadlineExceeded, wantCalls: 3},
+		{desc: "single file too large", files: 1, maxFiles: 0, err: tooLarge, wantCalls: 1, wantErr: true},
+		{desc: "max split depth", files: 32, maxFiles: 1, err: tooLarge, wantCalls: 5, wantErr: true},
+		{desc: "not splittable error", files: 8, maxFiles: 4, err: &config.PagesUploadError{StatusCode: http.StatusBadRequest}, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		b := testUploadBucket(t, tt.files)
+		u := &testBucketUploader{maxFiles: tt.maxFiles, err: tt.err}
+		progress := &pagesUploadProgress{totalFiles: tt.files}
+
+		err := uploadBucketSplitting(context.Background(), u.upload, b, progress, 0)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: uploadBucketSplitting() error = %v, wantErr %v", tt.desc, err, tt.wantErr)
+		}
+
+		if u.calls != tt.wantCalls {
+			t.Errorf("%s: uploadBucketSplitting() made %d upload calls, want %d", tt.desc, u.calls, tt.wantCalls)
+		}
+
+		if tt.wantErr {
+			continue
+		}
+
+		sort.Strings(u.uploaded)
+
+		if len(u.uploaded) != tt.files || progress.files != tt.files {
+			t.Errorf("%s: uploadBucketSplitting() uploaded %d files (progress %d), want %d", tt.desc, len(u.uploaded), progress.files, tt.files)
+		}
+
+		for i := 1; i < len(u.uploaded); i++ {
+			if u.uploaded[i] == u.uploaded[i-1] {
+				t.Errorf("%s: uploadBucketSplitting() uploaded %s more than once", tt.desc, u.uploaded[i])
+			}
+		}
+	}
+}
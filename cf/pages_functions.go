@@ -0,0 +1,94 @@
+package cf
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/zeebo/blake3"
+)
+
+const pagesWranglerBin = "wrangler"
+
+// PagesFunctionsHash returns hash covering names and contents of all files in functions directory,
+// so that changes can be detected without compiling it.
+func PagesFunctionsHash(dir string) (string, error) {
+	h := blake3.New()
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		_, _ = fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		_, err = io.Copy(h, f)
+
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+// BuildPagesFunctions compiles functions directory into a worker along with its routes using wrangler that has to be already installed,
+// so that no unpinned code is downloaded during deployment.
+func BuildPagesFunctions(ctx context.Context, functionsDir, outDir string) (workerFile, routesFile string, err error) {
+	bin, err := exec.LookPath(pagesWranglerBin)
+	if err != nil {
+		return "", "", fmt.Errorf("wrangler is required to build pages functions in '%s', install it with 'npm install -g wrangler' or add '%s' to build dir", functionsDir, PagesWorkerFile)
+	}
+
+	err = os.MkdirAll(outDir, 0o750)
+	if err != nil {
+		return "", "", err
+	}
+
+	workerFile = filepath.Join(outDir, PagesWorkerFile)
+	routesFile = filepath.Join(outDir, PagesRoutesFile)
+
+	_ = os.Remove(routesFile)
+
+	var out bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, bin, "pages", "functions", "build", functionsDir,
+		"--outfile", workerFile, "--output-routes-path", routesFile)
+	cmd.Dir = filepath.Dir(functionsDir)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err = cmd.Run()
+	if err != nil {
+		return "", "", fmt.Errorf("error building pages functions with wrangler: %w\n%s", err, strings.TrimSpace(out.String()))
+	}
+
+	return workerFile, routesFile, nil
+}
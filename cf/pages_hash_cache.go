@@ -0,0 +1,118 @@
+package cf
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// PagesHashVersion has to be bumped whenever PagesHashFile changes so that cached hashes are discarded.
+const PagesHashVersion = 1
+
+// pagesHashCacheMinAge is how old file modification has to be for its hash to be cached,
+// as files modified right before hashing could be modified again without mtime changing.
+const pagesHashCacheMinAge = 2 * time.Second
+
+type pagesHashCacheEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Hash    string `json:"hash"`
+}
+
+// PagesHashCache is an on-disk cache of file hashes keyed by path, size and modification time.
+type PagesHashCache struct {
+	Version int                             `json:"version"`
+	Files   map[string]*pagesHashCacheEntry `json:"files"`
+
+	path string
+	used map[string]*pagesHashCacheEntry
+	mu   sync.Mutex
+}
+
+// LoadPagesHashCache loads cache from path, starting with an empty one if it is missing, invalid or outdated.
+func LoadPagesHashCache(path string) *PagesHashCache {
+	c := &PagesHashCache{}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, c)
+	}
+
+	if err != nil || c.Version != PagesHashVersion || c.Files == nil {
+		c.Files = make(map[string]*pagesHashCacheEntry)
+	}
+
+	c.Version = PagesHashVersion
+	c.path = path
+	c.used = make(map[string]*pagesHashCacheEntry)
+
+	return c
+}
+
+func (c *PagesHashCache) Get(path string, info os.FileInfo) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.Files[path]
+	if !ok || e.Size != info.Size() || e.ModTime != info.ModTime().UnixNano() {
+		return "", false
+	}
+
+	c.used[path] = e
+
+	return e.Hash, true
+}
+
+func (c *PagesHashCache) Set(path string, info os.FileInfo, hash string) {
+	if c == nil || time.Since(info.ModTime()) < pagesHashCacheMinAge {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.used[path] = &pagesHashCacheEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Hash:    hash,
+	}
+}
+
+// Save writes cache with only the files that were looked up since it was loaded.
+func (c *PagesHashCache) Save() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.Marshal(&PagesHashCache{
+		Version: c.Version,
+		Files:   c.used,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(c.path), 0o750)
+	if err != nil {
+		return err
+	}
+
+	// Write to temporary file first so that interrupted save does not leave corrupted cache.
+	tmp := c.path + ".tmp"
+
+	err = os.WriteFile(tmp, data, 0o600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.path)
+}
@@ -0,0 +1,128 @@
+package cf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path, content string, modTime time.Time) os.FileInfo {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return info
+}
+
+func TestPagesHashCache(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "cache", "hashes.json")
+	old := time.Now().Add(-time.Hour)
+
+	fileA := filepath.Join(dir, "a.txt")
+	fileB := filepath.Join(dir, "b.txt")
+	fileC := filepath.Join(dir, "c.txt")
+
+	infoA := writeTestFile(t, fileA, "a", old)
+	infoB := writeTestFile(t, fileB, "b", old)
+	infoC := writeTestFile(t, fileC, "c", time.Now())
+
+	c := LoadPagesHashCache(cachePath)
+	c.Set(fileA, infoA, "hash-a")
+	c.Set(fileB, infoB, "hash-b")
+	c.Set(fileC, infoC, "hash-c")
+
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Modified file has to be hashed again.
+	infoB = writeTestFile(t, fileB, "bb", old)
+
+	c = LoadPagesHashCache(cachePath)
+
+	tests := []struct {
+		desc   string
+		path   string
+		info   os.FileInfo
+		want   string
+		wantOk bool
+	}{
+		{"cached", fileA, infoA, "hash-a", true},
+		{"modified", fileB, infoB, "", false},
+		{"recently modified", fileC, infoC, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := c.Get(tt.path, tt.info)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("%s: Get() = (%q, %v), want (%q, %v)", tt.desc, got, ok, tt.want, tt.wantOk)
+		}
+	}
+
+	// Only entries looked up since load are kept.
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	c = LoadPagesHashCache(cachePath)
+
+	if len(c.Files) != 1 || c.Files[fileA] == nil {
+		t.Errorf("Save() kept %v, want only %s", c.Files, fileA)
+	}
+}
+
+func TestLoadPagesHashCacheInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		desc    string
+		content string
+	}{
+		{"invalid json", "{"},
+		{"outdated version", `{"version": 0, "files": {"a": {"size": 1, "mtime": 1, "hash": "a"}}}`},
+		{"missing files", `{"version": 1}`},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.desc)
+
+		err := os.WriteFile(path, []byte(tt.content), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := LoadPagesHashCache(path)
+		if c.Version != PagesHashVersion || c.Files == nil || len(c.Files) != 0 {
+			t.Errorf("%s: LoadPagesHashCache() = version %d, files %v, want empty cache", tt.desc, c.Version, c.Files)
+		}
+	}
+}
+
+func TestPagesHashCacheNil(t *testing.T) {
+	var c *PagesHashCache
+
+	if _, ok := c.Get("a", nil); ok {
+		t.Error("Get() on nil cache returned cached hash")
+	}
+
+	c.Set("a", nil, "hash")
+
+	if err := c.Save(); err != nil {
+		t.Errorf("Save() on nil cache error = %v", err)
+	}
+}
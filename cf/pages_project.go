@@ -2,6 +2,7 @@ package cf
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/cloudflare/cloudflare-go"
@@ -17,7 +18,24 @@ type PagesProject struct {
 	AccountID fields.StringInputField `state:"force_new"`
 	Domains   fields.ArrayInputField
 
+	ProductionBranch  fields.StringInputField `default:"main"`
+	BuildCommand      fields.StringInputField
+	BuildOutputDir    fields.StringInputField
+	BuildRootDir      fields.StringInputField
+	CompatibilityDate fields.StringInputField
+	ProductionEnvVars fields.MapInputField
+	PreviewEnvVars    fields.MapInputField
+	// Secrets map names of secret env vars to their hashes (see PagesSecretHash), values are never stored in state.
+	ProductionSecrets fields.MapInputField
+	PreviewSecrets    fields.MapInputField
+	// Bindings map binding key (see PagesBindingKey) to ID of bound resource.
+	ProductionBindings fields.MapInputField
+	PreviewBindings    fields.MapInputField
+
 	InternalDomain fields.StringOutputField
+
+	ProductionSecretValues map[string]string `state:"-"`
+	PreviewSecretValues    map[string]string `state:"-"`
 }
 
 func (o *PagesProject) ReferenceID() string {
@@ -30,9 +48,21 @@ func (o *PagesProject) GetName() string {
 
 func (o *PagesProject) Read(ctx context.Context, meta interface{}) error {
 	pctx := meta.(*config.PluginContext)
-	cli := pctx.CloudflareClient()
+	wranglerCli := pctx.WranglerCloudflareClient()
+
+	proj, err := wranglerCli.PagesProject(ctx, o.Name.Any())
+	if err != nil {
+		var notFound *cloudflare.NotFoundError
+
+		if errors.As(err, &notFound) {
+			o.MarkAsNew()
+
+			return nil
+		}
+
+		return fmt.Errorf("error fetching pages project: %w", err)
+	}
 
-	proj, _ := cli.PagesProject(ctx, o.AccountID.Wanted(), o.Name.Wanted())
 	if proj.Name == "" {
 		o.MarkAsNew()
 
@@ -42,6 +72,83 @@ func (o *PagesProject) Read(ctx context.Context, meta interface{}) error {
 	o.MarkAsExisting()
 
 	o.InternalDomain.SetCurrent(proj.SubDomain)
+	o.ProductionBranch.SetCurrent(proj.ProductionBranch)
+
+	domains := make([]interface{}, 0, len(proj.Domains))
+
+	for _, d := range proj.Domains {
+		if d != proj.SubDomain {
+			domains = append(domains, d)
+		}
+	}
+
+	o.Domains.SetCurrent(domains)
+
+	setStringIfWanted(o.BuildCommand, proj.BuildConfig.BuildCommand)
+	setStringIfWanted(o.BuildOutputDir, proj.BuildConfig.DestinationDir)
+	setStringIfWanted(o.BuildRootDir, proj.BuildConfig.RootDir)
+	setStringIfWanted(o.CompatibilityDate, proj.DeploymentConfigs.Production.CompatibilityDate)
+
+	production := &proj.DeploymentConfigs.Production
+	preview := &proj.DeploymentConfigs.Preview
+
+	o.ProductionEnvVars.SetCurrent(pagesEnvVarsToMap(production.EnvVars))
+	o.PreviewEnvVars.SetCurrent(pagesEnvVarsToMap(preview.EnvVars))
+	o.ProductionSecrets.SetCurrent(pagesSecretsToMap(production.EnvVars, o.ProductionSecrets.Current()))
+	o.PreviewSecrets.SetCurrent(pagesSecretsToMap(preview.EnvVars, o.PreviewSecrets.Current()))
+	o.ProductionBindings.SetCurrent(pagesBindingsToMap(production))
+	o.PreviewBindings.SetCurrent(pagesBindingsToMap(preview))
+
+	return nil
+}
+
+func (o *PagesProject) updateProject(ctx context.Context, wranglerCli *config.WranglerCloudflareAPI) error {
+	deploymentConfig := func(vars, secrets fields.MapInputField, secretValues map[string]string, bindings fields.MapInputField) map[string]interface{} {
+		cfg := map[string]interface{}{
+			"env_vars": pagesEnvVarsPatch(vars.Current(), secrets.Current(), vars.Wanted(), secretValues),
+		}
+
+		for typ, b := range pagesBindingsPatch(bindings.Current(), bindings.Wanted()) {
+			cfg[typ] = b
+		}
+
+		if v, ok := o.CompatibilityDate.LookupWanted(); ok {
+			cfg["compatibility_date"] = v
+		}
+
+		return cfg
+	}
+
+	body := map[string]interface{}{
+		"production_branch": o.ProductionBranch.Wanted(),
+		"deployment_configs": map[string]interface{}{
+			"production": deploymentConfig(o.ProductionEnvVars, o.ProductionSecrets, o.ProductionSecretValues, o.ProductionBindings),
+			"preview":    deploymentConfig(o.PreviewEnvVars, o.PreviewSecrets, o.PreviewSecretValues, o.PreviewBindings),
+		},
+	}
+
+	buildConfig := make(map[string]interface{})
+
+	if v, ok := o.BuildCommand.LookupWanted(); ok {
+		buildConfig["build_command"] = v
+	}
+
+	if v, ok := o.BuildOutputDir.LookupWanted(); ok {
+		buildConfig["destination_dir"] = v
+	}
+
+	if v, ok := o.BuildRootDir.LookupWanted(); ok {
+		buildConfig["root_dir"] = v
+	}
+
+	if len(buildConfig) != 0 {
+		body["build_config"] = buildConfig
+	}
+
+	err := wranglerCli.UpdatePagesProject(ctx, o.Name.Wanted(), body)
+	if err != nil {
+		return fmt.Errorf("error updating pages project '%s': %w", o.Name.Wanted(), err)
+	}
 
 	return nil
 }
@@ -52,6 +159,9 @@ func (o *PagesProject) Create(ctx context.Context, meta interface{}) error {
 	cli := pctx.CloudflareClient()
 
 	proj, err := wranglerCli.CreatePagesProject(ctx, o.Name.Wanted())
+	if err != nil {
+		return err
+	}
 
 	o.InternalDomain.SetCurrent(proj.SubDomain)
 
@@ -66,12 +176,10 @@ func (o *PagesProject) Create(ctx context.Context, meta interface{}) error {
 		}
 	}
 
-	return err
+	return o.updateProject(ctx, wranglerCli)
 }
 
-func (o *PagesProject) Update(ctx context.Context, meta interface{}) error {
-	pctx := meta.(*config.PluginContext)
-	cli := pctx.CloudflareClient()
+func (o *PagesProject) updateDomains(ctx context.Context, cli *cloudflare.API) error {
 	m := make(map[string]bool)
 
 	for _, d := range o.Domains.Current() {
@@ -111,7 +219,19 @@ func (o *PagesProject) Update(ctx context.Context, meta interface{}) error {
 		}
 	}
 
-	return fmt.Errorf("unimplemented")
+	return nil
+}
+
+func (o *PagesProject) Update(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+
+	err := o.updateDomains(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	return o.updateProject(ctx, pctx.WranglerCloudflareClient())
 }
 
 func (o *PagesProject) Delete(ctx context.Context, meta interface{}) error {
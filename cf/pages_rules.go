@@ -0,0 +1,161 @@
+package cf
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	PagesHeadersFile   = "_headers"
+	PagesRedirectsFile = "_redirects"
+	PagesRoutesFile    = "_routes.json"
+	PagesWorkerFile    = "_worker.js"
+	PagesFunctionsDir  = "functions"
+
+	PagesMaxHeaderRules      = 100
+	PagesMaxHeaderLineLength = 2000
+	PagesMaxStaticRedirects  = 2000
+	PagesMaxDynamicRedirects = 100
+	PagesMaxRedirectLength   = 1000
+	PagesMaxRoutesRules      = 100
+	PagesMaxRouteLength      = 100
+	PagesRoutesVersion       = 1
+)
+
+// PagesRedirectStatuses are status codes supported in redirect rules, 200 being a rewrite.
+var PagesRedirectStatuses = map[int]bool{
+	200: true,
+	301: true,
+	302: true,
+	303: true,
+	307: true,
+	308: true,
+}
+
+func pagesRuleLines(content string) []string {
+	var ret []string
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		ret = append(ret, line)
+	}
+
+	return ret
+}
+
+// ValidatePagesRedirects checks _redirects file against cloudflare pages rule limits.
+func ValidatePagesRedirects(content string) error {
+	static, dynamic := 0, 0
+
+	for _, line := range pagesRuleLines(content) {
+		if len(line) > PagesMaxRedirectLength {
+			return fmt.Errorf("redirect rule '%s' exceeds %d characters", line, PagesMaxRedirectLength)
+		}
+
+		parts := strings.Fields(line)
+
+		if len(parts) < 2 || len(parts) > 3 {
+			return fmt.Errorf("invalid redirect rule '%s', expected: <from> <to> [status]", line)
+		}
+
+		if len(parts) == 3 {
+			status, err := strconv.Atoi(parts[2])
+			if err != nil || !PagesRedirectStatuses[status] {
+				return fmt.Errorf("invalid redirect rule '%s', unsupported status code '%s'", line, parts[2])
+			}
+		}
+
+		if strings.ContainsAny(parts[0], "*:") {
+			dynamic++
+		} else {
+			static++
+		}
+	}
+
+	if static > PagesMaxStaticRedirects {
+		return fmt.Errorf("too many static redirect rules: %d, max is %d", static, PagesMaxStaticRedirects)
+	}
+
+	if dynamic > PagesMaxDynamicRedirects {
+		return fmt.Errorf("too many dynamic redirect rules: %d, max is %d", dynamic, PagesMaxDynamicRedirects)
+	}
+
+	return nil
+}
+
+// ValidatePagesHeaders checks _headers file against cloudflare pages rule limits.
+func ValidatePagesHeaders(content string) error {
+	rules := 0
+
+	for _, line := range pagesRuleLines(content) {
+		if len(line) > PagesMaxHeaderLineLength {
+			return fmt.Errorf("headers line '%s' exceeds %d characters", line, PagesMaxHeaderLineLength)
+		}
+
+		// Headers are indented below the path they apply to.
+		if line[0] != ' ' && line[0] != '\t' {
+			rules++
+
+			continue
+		}
+
+		// Headers can also be detached with '! <name>'.
+		if rules == 0 || (!strings.Contains(line, ":") && !strings.HasPrefix(strings.TrimSpace(line), "!")) {
+			return fmt.Errorf("invalid headers line '%s', expected '<name>: <value>' indented below a path", strings.TrimSpace(line))
+		}
+	}
+
+	if rules > PagesMaxHeaderRules {
+		return fmt.Errorf("too many header rules: %d, max is %d", rules, PagesMaxHeaderRules)
+	}
+
+	return nil
+}
+
+// ValidatePagesRoutes checks _routes.json file that decides which requests invoke functions.
+func ValidatePagesRoutes(content string) error {
+	var routes struct {
+		Version int      `json:"version"`
+		Include []string `json:"include"`
+		Exclude []string `json:"exclude"`
+	}
+
+	err := json.Unmarshal([]byte(content), &routes)
+	if err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+
+	if routes.Version != PagesRoutesVersion {
+		return fmt.Errorf("unsupported version %d, expected %d", routes.Version, PagesRoutesVersion)
+	}
+
+	if len(routes.Include) == 0 {
+		return fmt.Errorf("at least one include rule is required")
+	}
+
+	if len(routes.Include)+len(routes.Exclude) > PagesMaxRoutesRules {
+		return fmt.Errorf("too many include and exclude rules: %d, max is %d", len(routes.Include)+len(routes.Exclude), PagesMaxRoutesRules)
+	}
+
+	for _, r := range append(routes.Include, routes.Exclude...) {
+		if !strings.HasPrefix(r, "/") {
+			return fmt.Errorf("rule '%s' has to start with '/'", r)
+		}
+
+		if len(r) > PagesMaxRouteLength {
+			return fmt.Errorf("rule '%s' exceeds %d characters", r, PagesMaxRouteLength)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package cf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func repeatLines(n int, format string) string {
+	lines := make([]string, n)
+
+	for i := range lines {
+		lines[i] = fmt.Sprintf(format, i)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestValidatePagesRedirects(t *testing.T) {
+	tests := []struct {
+		desc    string
+		content string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"comments and blank lines", "# comment\n\n  \n", false},
+		{"rules", "/a /b\n/c /d 301\n/blog/* /news/:splat 302\n/api/* /api.html 200", false},
+		{"missing target", "/a", true},
+		{"too many parts", "/a /b 301 extra", true},
+		{"invalid status", "/a /b abc", true},
+		{"unsupported status", "/a /b 404", true},
+		{"too long", "/a /" + strings.Repeat("b", PagesMaxRedirectLength), true},
+		{"max static", repeatLines(PagesMaxStaticRedirects, "/a%d /b"), false},
+		{"too many static", repeatLines(PagesMaxStaticRedirects+1, "/a%d /b"), true},
+		{"max dynamic", repeatLines(PagesMaxDynamicRedirects, "/a%d/* /b"), false},
+		{"too many dynamic", repeatLines(PagesMaxDynamicRedirects+1, "/a%d/:id /b"), true},
+	}
+
+	for _, tt := range tests {
+		err := ValidatePagesRedirects(tt.content)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidatePagesRedirects() error = %v, wantErr %v", tt.desc, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidatePagesHeaders(t *testing.T) {
+	tests := []struct {
+		desc    string
+		content string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"rules", "/*\n  X-Frame-Options: DENY\n\t! Content-Type\n# comment\n/static/*\n  Cache-Control: max-age=3600", false},
+		{"header without path", "  X-Frame-Options: DENY", true},
+		{"invalid header", "/*\n  X-Frame-Options", true},
+		{"too long", "/*\n  X-Test: " + strings.Repeat("a", PagesMaxHeaderLineLength), true},
+		{"max rules", repeatLines(PagesMaxHeaderRules, "/a%d\n  X-Test: 1"), false},
+		{"too many rules", repeatLines(PagesMaxHeaderRules+1, "/a%d\n  X-Test: 1"), true},
+	}
+
+	for _, tt := range tests {
+		err := ValidatePagesHeaders(tt.content)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidatePagesHeaders() error = %v, wantErr %v", tt.desc, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidatePagesRoutes(t *testing.T) {
+	tests := []struct {
+		desc    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"version": 1, "include": ["/*"], "exclude": ["/static/*"]}`, false},
+		{"invalid json", `{"version": 1`, true},
+		{"unsupported version", `{"version": 2, "include": ["/*"]}`, true},
+		{"missing include", `{"version": 1, "exclude": ["/static/*"]}`, true},
+		{"relative rule", `{"version": 1, "include": ["api/*"]}`, true},
+		{"relative exclude rule", `{"version": 1, "include": ["/*"], "exclude": ["static/*"]}`, true},
+		{"too long rule", `{"version": 1, "include": ["/` + strings.Repeat("a", PagesMaxRouteLength) + `"]}`, true},
+		{"too many rules", `{"version": 1, "include": ["/*"], "exclude": [` + strings.TrimSuffix(strings.Repeat(`"/a",`, PagesMaxRoutesRules), ",") + `]}`, true},
+	}
+
+	for _, tt := range tests {
+		err := ValidatePagesRoutes(tt.content)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidatePagesRoutes() error = %v, wantErr %v", tt.desc, err, tt.wantErr)
+		}
+	}
+}
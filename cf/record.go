@@ -3,6 +3,9 @@ package cf
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/cloudflare/cloudflare-go"
 	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
@@ -13,17 +16,63 @@ import (
 type DNSRecord struct {
 	registry.ResourceBase
 
-	ZoneID  fields.StringInputField `state:"force_new"`
-	Name    fields.StringInputField
-	Type    fields.StringInputField
-	Value   fields.StringInputField
-	Proxied fields.BoolInputField
-
-	ID fields.StringOutputField
+	ZoneID   fields.StringInputField `state:"force_new"`
+	Name     fields.StringInputField
+	Type     fields.StringInputField
+	Value    fields.StringInputField
+	Proxied  fields.BoolInputField
+	TTL      fields.IntInputField `default:"1"`
+	Priority fields.IntInputField
+	Data     fields.MapInputField
+	Owner    fields.StringInputField
+
+	ID            fields.StringOutputField
+	OwnerRecordID fields.StringOutputField
+
+	// Adopt allows to take over existing records not owned by this project.
+	Adopt bool `state:"-"`
 }
 
+const (
+	DNSRecordAutoTTL = 1
+	DNSRecordMinTTL  = 60
+	DNSRecordMaxTTL  = 86400
+)
+
+var (
+	// DNSProxiableTypes are record types that can be proxied through cloudflare.
+	DNSProxiableTypes = map[string]bool{
+		"A":     true,
+		"AAAA":  true,
+		"CNAME": true,
+	}
+
+	// DNSPriorityTypes are record types that require priority to be set.
+	DNSPriorityTypes = map[string]bool{
+		"MX":  true,
+		"URI": true,
+	}
+
+	// DNSSingletonTypes are record types that allow only one record with given name.
+	DNSSingletonTypes = map[string]bool{
+		"CNAME": true,
+	}
+
+	// DNSDataTypes are record types that use structured data instead of content, along with their required keys.
+	DNSDataTypes = map[string][]string{
+		"SRV": {"service", "proto", "name", "priority", "weight", "port", "target"},
+		"CAA": {"flags", "tag", "value"},
+	}
+
+	// DNSDataNumericKeys are keys of structured record data that cloudflare expects as numbers.
+	DNSDataNumericKeys = map[string]map[string]bool{
+		"SRV": {"priority": true, "weight": true, "port": true},
+		"CAA": {"flags": true},
+	}
+)
+
 func (o *DNSRecord) ReferenceID() string {
-	return fields.GenerateID("zones/%s/records/%s", o.ZoneID, o.Name)
+	return fields.GenerateID("zones/%s/records/%s/%s/%s", o.ZoneID, o.Name, o.Type, o.Value)
 }
 
 func (o *DNSRecord) GetName() string {
@@ -50,16 +99,22 @@ func (o *DNSRecord) Read(ctx context.Context, meta interface{}) error {
 
 	var rec *cloudflare.DNSRecord
 
-	for _, r := range records.([]cloudflare.DNSRecord) { //nolint: gocritic
+	recordList := records.([]cloudflare.DNSRecord)
+
+	for _, r := range recordList { //nolint: gocritic
 		if o.ID.Current() == r.ID {
 			rec = &r //nolint
 			break
 		}
 	}
 
+	if rec != nil {
+		pctx.AddStateDNSRecord(zoneID, rec.ID)
+	}
+
 	if rec == nil {
-		for _, r := range records.([]cloudflare.DNSRecord) { //nolint: gocritic
-			if o.Name.Any() == r.Name {
+		for _, r := range recordList { //nolint: gocritic
+			if o.matches(&r) {
 				rec = &r //nolint
 				break
 			}
@@ -72,12 +127,46 @@ func (o *DNSRecord) Read(ctx context.Context, meta interface{}) error {
 		return nil
 	}
 
+	// Record that is no longer wanted under this identity but is claimed by another wanted record (e.g. identity scheme changed), should not be deleted.
+	if o.Name.Wanted() == "" && pctx.IsDNSRecordWanted(zoneID, DNSRecordKey(rec.Name, rec.Type, dnsRecordContentKey(rec))) {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	owner := o.readOwner(recordList, rec)
+
+	switch {
+	case rec.ID == o.ID.Current() && owner == "":
+		// Record managed according to state, created before ownership records were introduced.
+		// Claim it as is, ownership record is created with its next update.
+		owner = o.Owner.Wanted()
+	case rec.ID != o.ID.Current() && owner == "" && !o.Adopt:
+		// Record may still be managed in state under a previous identity, which is known only once all records are read.
+		pctx.AddUnownedDNSRecord(zoneID, rec.ID, fmt.Errorf("dns record '%s' (%s) already exists and is not managed by outblocks, set 'cloudflare_adopt' property on domain to take it over", rec.Name, rec.Type))
+	case rec.ID != o.ID.Current() && owner != DNSOwnerID(pctx.Env()) && !o.Adopt:
+		return fmt.Errorf("dns record '%s' (%s) already exists and is owned by '%s', set 'cloudflare_adopt' property on domain to take it over", rec.Name, rec.Type, owner)
+	}
+
 	o.MarkAsExisting()
+	o.Owner.SetCurrent(owner)
 	o.ZoneID.SetCurrent(zoneID)
 	o.ID.SetCurrent(rec.ID)
 	o.Name.SetCurrent(rec.Name)
 	o.Type.SetCurrent(rec.Type)
-	o.Value.SetCurrent(rec.Content)
+	o.TTL.SetCurrent(rec.TTL)
+
+	if _, ok := DNSDataTypes[rec.Type]; ok {
+		// Content of structured records is computed by cloudflare from data, so value is its canonical form instead.
+		o.Value.SetCurrent(dnsRecordContentKey(rec))
+		o.Data.SetCurrent(dnsRecordDataToMap(rec.Data))
+	} else {
+		o.Value.SetCurrent(rec.Content)
+	}
+
+	if _, ok := o.Priority.LookupWanted(); ok && rec.Priority != nil {
+		o.Priority.SetCurrent(int(*rec.Priority))
+	}
 
 	proxied := false
 
@@ -90,17 +179,182 @@ func (o *DNSRecord) Read(ctx context.Context, meta interface{}) error {
 	return nil
 }
 
+// Validate checks if wanted record is a valid cloudflare record so that invalid combinations fail during plan.
+func (o *DNSRecord) Validate() error {
+	name := o.Name.Wanted()
+	typ := o.Type.Wanted()
+
+	if o.Proxied.Wanted() && !DNSProxiableTypes[typ] {
+		return fmt.Errorf("dns record '%s': %s records cannot be proxied", name, typ)
+	}
+
+	ttl := o.TTL.Wanted()
+
+	if o.Proxied.Wanted() && ttl != DNSRecordAutoTTL {
+		return fmt.Errorf("dns record '%s': ttl of proxied records is always automatic (%d)", name, DNSRecordAutoTTL)
+	}
+
+	if ttl != DNSRecordAutoTTL && (ttl < DNSRecordMinTTL || ttl > DNSRecordMaxTTL) {
+		return fmt.Errorf("dns record '%s': ttl has to be either %d (automatic) or between %d and %d", name, DNSRecordAutoTTL, DNSRecordMinTTL, DNSRecordMaxTTL)
+	}
+
+	if _, ok := o.Priority.LookupWanted(); !ok && DNSPriorityTypes[typ] {
+		return fmt.Errorf("dns record '%s': %s records require priority", name, typ)
+	}
+
+	if keys, ok := DNSDataTypes[typ]; ok {
+		data := o.Data.Wanted()
+
+		for _, k := range keys {
+			if _, ok := data[k]; !ok {
+				return fmt.Errorf("dns record '%s': %s records require data.%s to be set", name, typ, k)
+			}
+		}
+
+		_, err := dnsRecordDataFromMap(typ, data)
+		if err != nil {
+			return fmt.Errorf("dns record '%s': %w", name, err)
+		}
+
+		return nil
+	}
+
+	if o.Value.Wanted() == "" {
+		return fmt.Errorf("dns record '%s': value is required", name)
+	}
+
+	return nil
+}
+
+// DNSRecordKey returns identity of a record within a zone. Multi-value record sets are distinguished by their content.
+func DNSRecordKey(name, typ, value string) string {
+	name = strings.ToLower(name)
+	typ = strings.ToUpper(typ)
+
+	if DNSSingletonTypes[typ] {
+		return fmt.Sprintf("%s::TYPE_%s", name, typ)
+	}
+
+	return fmt.Sprintf("%s::TYPE_%s::%s", name, typ, strings.ToLower(strings.TrimSuffix(value, ".")))
+}
+
+// Key returns identity of wanted record.
+func (o *DNSRecord) Key() string {
+	value := o.Value.Wanted()
+
+	if _, ok := DNSDataTypes[o.Type.Wanted()]; ok {
+		value = DNSRecordDataKey(o.Data.Wanted())
+	}
+
+	return DNSRecordKey(o.Name.Wanted(), o.Type.Wanted(), value)
+}
+
+// DNSRecordDataKey returns canonical representation of structured record data.
+func DNSRecordDataKey(data map[string]interface{}) string {
+	keys := make([]string, 0, len(data))
+
+	for k, v := range data {
+		keys = append(keys, fmt.Sprintf("%s=%v", k, v))
+	}
+
+	sort.Strings(keys)
+
+	return strings.Join(keys, ",")
+}
+
+func dnsRecordContentKey(r *cloudflare.DNSRecord) string {
+	if _, ok := DNSDataTypes[r.Type]; ok {
+		return DNSRecordDataKey(dnsRecordDataToMap(r.Data))
+	}
+
+	return strings.ToLower(strings.TrimSuffix(r.Content, "."))
+}
+
+func isSameDNSContent(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
+
+// matches checks if existing record can be adopted as this one. Records of a different type are never adopted.
+func (o *DNSRecord) matches(r *cloudflare.DNSRecord) bool {
+	typ := o.Type.Any()
+
+	if !strings.EqualFold(o.Name.Any(), r.Name) || typ != r.Type {
+		return false
+	}
+
+	if DNSSingletonTypes[typ] {
+		return true
+	}
+
+	if _, ok := DNSDataTypes[typ]; ok {
+		return DNSRecordDataKey(o.Data.Any()) == DNSRecordDataKey(dnsRecordDataToMap(r.Data))
+	}
+
+	return isSameDNSContent(o.Value.Any(), r.Content)
+}
+
+func dnsRecordDataToMap(data interface{}) map[string]interface{} {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	ret := make(map[string]interface{}, len(m))
+
+	for k, v := range m {
+		ret[k] = fmt.Sprint(v)
+	}
+
+	return ret
+}
+
+func dnsRecordDataFromMap(typ string, m map[string]interface{}) (map[string]interface{}, error) {
+	ret := make(map[string]interface{}, len(m))
+	numeric := DNSDataNumericKeys[typ]
+
+	for k, v := range m {
+		str := fmt.Sprint(v)
+
+		if !numeric[k] {
+			ret[k] = str
+
+			continue
+		}
+
+		i, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, fmt.Errorf("%s record data.%s has to be a number, got '%s'", typ, k, str)
+		}
+
+		ret[k] = i
+	}
+
+	return ret, nil
+}
+
 func (o *DNSRecord) createDNSRecord() *cloudflare.DNSRecord {
 	rec := cloudflare.DNSRecord{
 		Name:    o.Name.Wanted(),
 		Type:    o.Type.Wanted(),
 		Content: o.Value.Wanted(),
+		TTL:     o.TTL.Wanted(),
 	}
 
 	if val, ok := o.Proxied.LookupWanted(); ok {
 		rec.Proxied = &val
 	}
 
+	if val, ok := o.Priority.LookupWanted(); ok {
+		priority := uint16(val)
+		rec.Priority = &priority
+	}
+
+	if _, ok := DNSDataTypes[rec.Type]; ok {
+		// Data is validated during plan already.
+		rec.Content = ""
+		rec.Data, _ = dnsRecordDataFromMap(rec.Type, o.Data.Wanted())
+	}
+
 	return &rec
 }
 
@@ -115,19 +369,38 @@ func (o *DNSRecord) Create(ctx context.Context, meta interface{}) error {
 
 	o.ID.SetCurrent(rec.Result.ID)
 
-	return nil
+	return o.upsertOwnerRecord(ctx, cli)
 }
 
 func (o *DNSRecord) Update(ctx context.Context, meta interface{}) error {
 	pctx := meta.(*config.PluginContext)
 	cli := pctx.CloudflareClient()
 
-	return cli.UpdateDNSRecord(ctx, o.ZoneID.Current(), o.ID.Current(), *o.createDNSRecord())
+	err := cli.UpdateDNSRecord(ctx, o.ZoneID.Current(), o.ID.Current(), *o.createDNSRecord())
+	if err != nil {
+		return err
+	}
+
+	return o.upsertOwnerRecord(ctx, cli)
 }
 
 func (o *DNSRecord) Delete(ctx context.Context, meta interface{}) error {
 	pctx := meta.(*config.PluginContext)
 	cli := pctx.CloudflareClient()
 
-	return cli.DeleteDNSRecord(ctx, o.ZoneID.Current(), o.ID.Current())
+	// Record was taken over by someone else, leave it alone.
+	if owner := o.Owner.Current(); owner != "" && owner != DNSOwnerID(pctx.Env()) {
+		return nil
+	}
+
+	err := cli.DeleteDNSRecord(ctx, o.ZoneID.Current(), o.ID.Current())
+	if err != nil {
+		return err
+	}
+
+	if o.OwnerRecordID.Current() == "" {
+		return nil
+	}
+
+	return cli.DeleteDNSRecord(ctx, o.ZoneID.Current(), o.OwnerRecordID.Current())
 }
@@ -0,0 +1,113 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/outblocks/outblocks-plugin-go/env"
+	"github.com/outblocks/outblocks-plugin-go/util"
+)
+
+// Ownership of DNS records is tracked with companion TXT records (similar to external-dns registry),
+// so that records not created by outblocks are never silently taken over.
+const (
+	dnsOwnerRecordPrefix = "_outblocks"
+	dnsOwnerHeritage     = "heritage=outblocks"
+	dnsOwnerKey          = "outblocks/owner="
+	dnsOwnerRecordKey    = "outblocks/record="
+)
+
+func DNSOwnerID(e env.Enver) string {
+	return fmt.Sprintf("%s/%s", e.ProjectID(), e.Env())
+}
+
+func dnsOwnerRecordName(name, typ string) string {
+	name = strings.ToLower(name)
+
+	if strings.HasPrefix(name, "*.") {
+		name = "_wildcard." + name[2:]
+	}
+
+	return fmt.Sprintf("%s.%s.%s", dnsOwnerRecordPrefix, strings.ToLower(typ), name)
+}
+
+func dnsOwnerRecordMarker(key string) string {
+	return dnsOwnerRecordKey + util.LimitString(util.SHAString(key), 16)
+}
+
+func dnsOwnerRecordContent(owner, key string) string {
+	return fmt.Sprintf("%s,%s%s,%s", dnsOwnerHeritage, dnsOwnerKey, owner, dnsOwnerRecordMarker(key))
+}
+
+func parseDNSOwner(content string) string {
+	content = strings.Trim(content, `"`)
+
+	if !strings.HasPrefix(content, dnsOwnerHeritage) {
+		return ""
+	}
+
+	for _, part := range strings.Split(content, ",") {
+		if strings.HasPrefix(part, dnsOwnerKey) {
+			return strings.TrimPrefix(part, dnsOwnerKey)
+		}
+	}
+
+	return ""
+}
+
+// readOwner finds ownership record of rec and returns its owner.
+func (o *DNSRecord) readOwner(records []cloudflare.DNSRecord, rec *cloudflare.DNSRecord) string {
+	ownerName := dnsOwnerRecordName(rec.Name, rec.Type)
+	marker := ""
+
+	if o.Name.Wanted() != "" {
+		marker = dnsOwnerRecordMarker(o.Key())
+	}
+
+	for _, r := range records { //nolint: gocritic
+		if r.Type != "TXT" {
+			continue
+		}
+
+		if (o.OwnerRecordID.Current() != "" && r.ID == o.OwnerRecordID.Current()) ||
+			(marker != "" && strings.EqualFold(r.Name, ownerName) && strings.Contains(r.Content, marker)) {
+			o.OwnerRecordID.SetCurrent(r.ID)
+
+			return parseDNSOwner(r.Content)
+		}
+	}
+
+	o.OwnerRecordID.SetCurrent("")
+
+	return ""
+}
+
+func (o *DNSRecord) upsertOwnerRecord(ctx context.Context, cli *cloudflare.API) error {
+	owner := o.Owner.Wanted()
+	if owner == "" {
+		return nil
+	}
+
+	zoneID := o.ZoneID.Wanted()
+	rec := cloudflare.DNSRecord{
+		Name:    dnsOwnerRecordName(o.Name.Wanted(), o.Type.Wanted()),
+		Type:    "TXT",
+		Content: dnsOwnerRecordContent(owner, o.Key()),
+		TTL:     DNSRecordAutoTTL,
+	}
+
+	if id := o.OwnerRecordID.Current(); id != "" {
+		return cli.UpdateDNSRecord(ctx, zoneID, id, rec)
+	}
+
+	res, err := cli.CreateDNSRecord(ctx, zoneID, rec)
+	if err != nil {
+		return fmt.Errorf("error creating ownership record of '%s': %w", o.Name.Wanted(), err)
+	}
+
+	o.OwnerRecordID.SetCurrent(res.Result.ID)
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package cf
+
+import "testing"
+
+func TestParseDNSOwner(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"heritage=outblocks,outblocks/owner=project/dev,outblocks/record=abc", "project/dev"},
+		{`"heritage=outblocks,outblocks/owner=project/dev,outblocks/record=abc"`, "project/dev"},
+		{"heritage=outblocks,outblocks/record=abc", ""},
+		{"heritage=external-dns,external-dns/owner=default", ""},
+		{"outblocks/owner=project/dev", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := parseDNSOwner(tt.content); got != tt.want {
+			t.Errorf("parseDNSOwner(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestDNSOwnerRecordContentRoundtrip(t *testing.T) {
+	if got := parseDNSOwner(dnsOwnerRecordContent("project/dev", "example.com::TYPE_A::1.2.3.4")); got != "project/dev" {
+		t.Errorf("parseDNSOwner(dnsOwnerRecordContent()) = %q, want %q", got, "project/dev")
+	}
+}
+
+func TestDNSOwnerRecordName(t *testing.T) {
+	tests := []struct {
+		name, typ string
+		want      string
+	}{
+		{"example.com", "A", "_outblocks.a.example.com"},
+		{"WWW.Example.com", "CNAME", "_outblocks.cname.www.example.com"},
+		{"*.example.com", "A", "_outblocks.a._wildcard.example.com"},
+		{"*.app.example.com", "AAAA", "_outblocks.aaaa._wildcard.app.example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := dnsOwnerRecordName(tt.name, tt.typ); got != tt.want {
+			t.Errorf("dnsOwnerRecordName(%q, %q) = %q, want %q", tt.name, tt.typ, got, tt.want)
+		}
+	}
+}
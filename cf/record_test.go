@@ -0,0 +1,115 @@
+package cf
+
+import (
+	"testing"
+
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+)
+
+func TestDNSRecordValidate(t *testing.T) {
+	srv := func(port string) map[string]string {
+		return map[string]string{
+			"service":  "_sip",
+			"proto":    "_tcp",
+			"name":     "example.com",
+			"priority": "10",
+			"weight":   "5",
+			"port":     port,
+			"target":   "sip.example.com",
+		}
+	}
+
+	tests := []struct {
+		desc    string
+		typ     string
+		value   string
+		proxied bool
+		ttl     int
+		data    map[string]string
+		wantErr bool
+	}{
+		{desc: "a record", typ: "A", value: "1.2.3.4", ttl: DNSRecordAutoTTL},
+		{desc: "proxied a record", typ: "A", value: "1.2.3.4", proxied: true, ttl: DNSRecordAutoTTL},
+		{desc: "proxied txt record", typ: "TXT", value: "test", proxied: true, ttl: DNSRecordAutoTTL, wantErr: true},
+		{desc: "proxied record with ttl", typ: "A", value: "1.2.3.4", proxied: true, ttl: 300, wantErr: true},
+		{desc: "ttl in range", typ: "A", value: "1.2.3.4", ttl: DNSRecordMinTTL},
+		{desc: "ttl too low", typ: "A", value: "1.2.3.4", ttl: 30, wantErr: true},
+		{desc: "ttl too high", typ: "A", value: "1.2.3.4", ttl: DNSRecordMaxTTL + 1, wantErr: true},
+		{desc: "missing value", typ: "A", ttl: DNSRecordAutoTTL, wantErr: true},
+		{desc: "srv record", typ: "SRV", ttl: DNSRecordAutoTTL, data: srv("5060")},
+		{desc: "srv record with invalid port", typ: "SRV", ttl: DNSRecordAutoTTL, data: srv("sip"), wantErr: true},
+		{desc: "srv record missing data", typ: "SRV", ttl: DNSRecordAutoTTL, data: map[string]string{"port": "5060"}, wantErr: true},
+		{desc: "caa record", typ: "CAA", ttl: DNSRecordAutoTTL, data: map[string]string{"flags": "0", "tag": "issue", "value": "letsencrypt.org"}},
+	}
+
+	for _, tt := range tests {
+		data := make(map[string]fields.Field, len(tt.data))
+
+		for k, v := range tt.data {
+			data[k] = fields.String(v)
+		}
+
+		o := &DNSRecord{
+			Name:     fields.String("example.com"),
+			Type:     fields.String(tt.typ),
+			Value:    fields.String(tt.value),
+			Proxied:  fields.Bool(tt.proxied),
+			TTL:      fields.Int(tt.ttl),
+			Priority: fields.Int(10),
+			Data:     fields.Map(data),
+		}
+
+		err := o.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.desc, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDNSRecordDataFromMap(t *testing.T) {
+	got, err := dnsRecordDataFromMap("CAA", map[string]interface{}{"flags": "128", "tag": "issue", "value": "ca.example.com"})
+	if err != nil {
+		t.Fatalf("dnsRecordDataFromMap() error = %v", err)
+	}
+
+	if got["flags"] != 128 || got["tag"] != "issue" || got["value"] != "ca.example.com" {
+		t.Errorf("dnsRecordDataFromMap() = %v", got)
+	}
+}
+
+func TestDNSRecordKey(t *testing.T) {
+	tests := []struct {
+		name, typ, value string
+		want             string
+	}{
+		{"example.com", "A", "1.2.3.4", "example.com::TYPE_A::1.2.3.4"},
+		{"Example.COM", "a", "1.2.3.4", "example.com::TYPE_A::1.2.3.4"},
+		{"example.com", "MX", "Mail.Example.com.", "example.com::TYPE_MX::mail.example.com"},
+		{"www.example.com", "CNAME", "a.example.com", "www.example.com::TYPE_CNAME"},
+		{"www.example.com", "cname", "b.example.com", "www.example.com::TYPE_CNAME"},
+	}
+
+	for _, tt := range tests {
+		if got := DNSRecordKey(tt.name, tt.typ, tt.value); got != tt.want {
+			t.Errorf("DNSRecordKey(%q, %q, %q) = %q, want %q", tt.name, tt.typ, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDNSRecordDataKey(t *testing.T) {
+	tests := []struct {
+		data map[string]interface{}
+		want string
+	}{
+		{nil, ""},
+		{map[string]interface{}{"flags": 0, "tag": "issue", "value": "letsencrypt.org"}, "flags=0,tag=issue,value=letsencrypt.org"},
+		{map[string]interface{}{"value": "letsencrypt.org", "flags": "0", "tag": "issue"}, "flags=0,tag=issue,value=letsencrypt.org"},
+		{map[string]interface{}{"port": 5060, "priority": 10.0}, "port=5060,priority=10"},
+	}
+
+	for _, tt := range tests {
+		if got := DNSRecordDataKey(tt.data); got != tt.want {
+			t.Errorf("DNSRecordDataKey(%v) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
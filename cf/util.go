@@ -10,8 +10,12 @@ import (
 )
 
 var Types = []registry.Resource{
+	(*Zone)(nil),
+	(*ZoneSSLSetting)(nil),
 	(*DNSRecord)(nil),
 	(*OriginCertificate)(nil),
+	(*OriginPulls)(nil),
+	(*KVNamespace)(nil),
 	(*PagesProject)(nil),
 	(*PagesFiles)(nil),
 	(*PagesDeployment)(nil),
@@ -45,6 +49,22 @@ func ID(e env.Enver, resourceID string) string {
 	return fmt.Sprintf("%s-%s-%s", sanitizedID, sanitizedEnv, ShortShaID(e.ProjectID()))
 }
 
+// SharedID is like ID but without environment, for resources shared between environments.
+func SharedID(e env.Enver, resourceID string) string {
+	sanitizedID := util.SanitizeName(resourceID, false, false)
+
+	if len(sanitizedID) > 49 {
+		sanitizedID = util.LimitString(sanitizedID, 45) + ShortShaID(sanitizedID)
+	}
+
+	return fmt.Sprintf("%s-%s", sanitizedID, ShortShaID(e.ProjectID()))
+}
+
+// PagesBranch returns branch that preview deployments of environment are made on. Branch alias is limited to 28 characters.
+func PagesBranch(e env.Enver) string {
+	return util.LimitString(util.SanitizeName(e.Env(), false, false), 28)
+}
+
 func FixURL(url string) string {
 	split := strings.SplitN(url, "://", 2)
 	if len(split) == 2 {
@@ -0,0 +1,349 @@
+package cf
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
+	"github.com/zeebo/blake3"
+)
+
+const (
+	WorkerFormatModules       = "modules"
+	WorkerFormatServiceWorker = "service-worker"
+)
+
+const (
+	// WorkerDefaultMaxCompressedSize is compressed size limit of workers on paid plan.
+	WorkerDefaultMaxCompressedSize = 10 * 1024 * 1024
+	workerScriptPart               = "script"
+)
+
+// WorkerModuleTypes map extensions of files uploaded as modules to their part content type.
+var WorkerModuleTypes = map[string]string{
+	".js":   "application/javascript+module",
+	".mjs":  "application/javascript+module",
+	".cjs":  "application/javascript",
+	".wasm": "application/wasm",
+	".txt":  "text/plain",
+	".html": "text/plain",
+	".bin":  "application/octet-stream",
+}
+
+// WorkerBlobBindingTypes map extensions of files bound to service worker format workers to their binding type.
+var WorkerBlobBindingTypes = map[string]string{
+	".wasm": "wasm_module",
+	".txt":  "text_blob",
+	".html": "text_blob",
+	".bin":  "data_blob",
+}
+
+var workerBindingNameRegex = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+var workerDefaultExportRegex = regexp.MustCompile(`\bexport\s+default\b|\bexport\s*\{[^}]*\bas\s+default\b`)
+
+// workerImportRegex matches specifiers of static imports, re-exports and dynamic imports with string literals.
+var workerImportRegex = regexp.MustCompile(`(?:\bimport\s*(?:[\w$*{}\s,]+?\s*from\s*)?|\bexport\s*(?:\*(?:\s*as\s+[\w$]+)?|\{[^}]*\})\s*from\s*|\bimport\s*\(\s*)["']([^"'\n]+)["']`)
+
+type WorkerModule struct {
+	Name        string
+	Path        string
+	ContentType string
+}
+
+// IsWorkerModuleScript returns true if script is in ES module format, i.e. it has a default export.
+func IsWorkerModuleScript(content []byte) bool {
+	return workerDefaultExportRegex.Match(content)
+}
+
+func findWorkerFiles(root string, types map[string]string) ([]*WorkerModule, error) {
+	var ret []*WorkerModule
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		ext := filepath.Ext(path)
+		if _, ok := types[ext]; !ok {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		ret = append(ret, &WorkerModule{
+			Name:        filepath.ToSlash(rel),
+			Path:        path,
+			ContentType: WorkerModuleTypes[ext],
+		})
+
+		return nil
+	})
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+
+	return ret, err
+}
+
+// WorkerModuleImports returns relative imports of module, i.e. other modules that have to be uploaded along with it.
+func WorkerModuleImports(content []byte) []string {
+	var ret []string
+
+	for _, m := range workerImportRegex.FindAllSubmatch(content, -1) {
+		spec := string(m[1])
+
+		if strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") {
+			ret = append(ret, spec)
+		}
+	}
+
+	return ret
+}
+
+// FindWorkerModules finds modules of worker in build dir, with main module being first.
+// Modules matching patterns (relative to build dir) are used if set, otherwise modules imported by main module, directly or not.
+func FindWorkerModules(root, main string, patterns []string) ([]*WorkerModule, error) {
+	modules, err := findWorkerFiles(root, WorkerModuleTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	main = filepath.ToSlash(main)
+	byName := make(map[string]*WorkerModule, len(modules))
+
+	for _, m := range modules {
+		byName[m.Name] = m
+	}
+
+	if _, ok := byName[main]; !ok {
+		return nil, fmt.Errorf("main module '%s' not found in '%s'", main, root)
+	}
+
+	var names []string
+
+	if len(patterns) != 0 {
+		names, err = matchWorkerModules(modules, main, patterns)
+	} else {
+		names, err = importedWorkerModules(byName, main)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*WorkerModule, len(names))
+
+	for i, name := range names {
+		ret[i] = byName[name]
+	}
+
+	return ret, nil
+}
+
+func matchWorkerModules(modules []*WorkerModule, main string, patterns []string) ([]string, error) {
+	ret := []string{main}
+
+	for _, m := range modules {
+		if m.Name == main {
+			continue
+		}
+
+		for _, p := range patterns {
+			ok, err := path.Match(p, m.Name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid modules pattern '%s': %w", p, err)
+			}
+
+			if ok {
+				ret = append(ret, m.Name)
+
+				break
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// importedWorkerModules follows relative imports starting from main module. Imports that cannot be resolved are skipped,
+// as they may come from code that is never executed (e.g. comments) and cloudflare reports missing modules on upload anyway.
+func importedWorkerModules(modules map[string]*WorkerModule, main string) ([]string, error) {
+	ret := []string{main}
+	seen := map[string]bool{main: true}
+
+	for i := 0; i < len(ret); i++ {
+		m := modules[ret[i]]
+
+		if m.ContentType != WorkerModuleTypes[".js"] && m.ContentType != WorkerModuleTypes[".cjs"] {
+			continue
+		}
+
+		content, err := os.ReadFile(m.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		var imports []string
+
+		for _, spec := range WorkerModuleImports(content) {
+			name := path.Join(path.Dir(m.Name), spec)
+
+			if _, ok := modules[name]; !ok || seen[name] {
+				continue
+			}
+
+			seen[name] = true
+
+			imports = append(imports, name)
+		}
+
+		sort.Strings(imports)
+
+		ret = append(ret, imports...)
+	}
+
+	// Keep main module first and the rest sorted, so that order does not depend on import order.
+	sort.Strings(ret[1:])
+
+	return ret, nil
+}
+
+// WorkerBlobBindingName returns name of binding that blob of service worker format worker is available as,
+// e.g. 'my_module' for 'my-module.wasm'.
+func WorkerBlobBindingName(name string) string {
+	name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	name = workerBindingNameRegex.ReplaceAllString(name, "_")
+
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+
+	return name
+}
+
+// FindWorkerBlobs finds WebAssembly, text and data files in build dir, to be bound to service worker format worker.
+func FindWorkerBlobs(root string) ([]*WorkerModule, error) {
+	ret, err := findWorkerFiles(root, WorkerBlobBindingTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+
+	for _, m := range ret {
+		name := WorkerBlobBindingName(m.Name)
+
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("files '%s' and '%s' would both be bound as '%s'", other, m.Name, name)
+		}
+
+		names[name] = m.Name
+	}
+
+	return ret, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+
+	return len(p), nil
+}
+
+// WorkerCompressedSize returns estimated compressed size of worker files, which is what worker size limit applies to.
+// Files are compressed as a single gzip stream, so it is only an estimate of what cloudflare measures.
+func WorkerCompressedSize(paths []string) (int64, error) {
+	cw := &countingWriter{}
+	gw := gzip.NewWriter(cw)
+
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = io.Copy(gw, f)
+		f.Close()
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err := gw.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	return cw.n, nil
+}
+
+// WorkerModulesHash returns hash covering names, types and contents of all modules.
+func WorkerModulesHash(modules []*WorkerModule) (string, error) {
+	h := blake3.New()
+
+	for _, m := range modules {
+		f, err := os.Open(m.Path)
+		if err != nil {
+			return "", err
+		}
+
+		info, err := f.Stat()
+		if err == nil {
+			_, _ = fmt.Fprintf(h, "%s\x00%s\x00%d\x00", m.Name, m.ContentType, info.Size())
+			_, err = io.Copy(h, f)
+		}
+
+		f.Close()
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+func readWorkerModules(modules []*WorkerModule) ([]*config.WorkerModule, error) {
+	ret := make([]*config.WorkerModule, len(modules))
+
+	for i, m := range modules {
+		content, err := os.ReadFile(m.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		ret[i] = &config.WorkerModule{
+			Name:        m.Name,
+			ContentType: m.ContentType,
+			Content:     content,
+		}
+	}
+
+	return ret, nil
+}
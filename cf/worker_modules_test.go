@@ -0,0 +1,133 @@
+package cf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsWorkerModuleScript(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"export default { fetch() {} }", true},
+		{"export   default\nworker", true},
+		{"const worker = {}; export { worker as default };", true},
+		{"export { a, worker as default }", true},
+		{"addEventListener('fetch', e => e.respondWith(handle(e)))", false},
+		{"export const defaults = {}", false},
+		{"export { worker }", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsWorkerModuleScript([]byte(tt.content)); got != tt.want {
+			t.Errorf("IsWorkerModuleScript(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestWorkerModuleImports(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{`import "./polyfill.js";`, []string{"./polyfill.js"}},
+		{`import a from './a.js'; import { b, c } from "../b.mjs"; import * as d from './d.js'`, []string{"./a.js", "../b.mjs", "./d.js"}},
+		{`import a, { b } from './a.js'`, []string{"./a.js"}},
+		{`export * from './a.js'; export * as b from './b.js'; export { c } from './c.js'`, []string{"./a.js", "./b.js", "./c.js"}},
+		{`const m = await import('./lazy.js'); import wasm from './module.wasm'`, []string{"./lazy.js", "./module.wasm"}},
+		{`import{a}from"./min.js"`, []string{"./min.js"}},
+		{`import { connect } from 'cloudflare:sockets'; import lodash from "lodash"`, nil},
+		{`const s = "import"; foo('./a.js')`, nil},
+	}
+
+	for _, tt := range tests {
+		if got := WorkerModuleImports([]byte(tt.content)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("WorkerModuleImports(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestWorkerBlobBindingName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"module.wasm", "module"},
+		{"my-module.wasm", "my_module"},
+		{"assets/index.html", "index"},
+		{"data.v2.bin", "data_v2"},
+		{"1st.txt", "_1st"},
+		{".txt", "_"},
+	}
+
+	for _, tt := range tests {
+		if got := WorkerBlobBindingName(tt.name); got != tt.want {
+			t.Errorf("WorkerBlobBindingName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindWorkerModules(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"index.js":                  `import { a } from './lib/a.js'; export default { fetch() { return import("./lazy.mjs") } }`,
+		"lib/a.js":                  `import b from '../b.js'; import wasm from './a.wasm'; import missing from './missing.js'; export const a = 1`,
+		"lib/a.wasm":                "",
+		"b.js":                      `import { a } from './lib/a.js'; export default 1`,
+		"lazy.mjs":                  "export default 1",
+		"unused.js":                 "export default 1",
+		"readme.md":                 "",
+		"node_modules/dep/index.js": "export default 1",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		err := os.MkdirAll(filepath.Dir(path), 0o750)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		desc     string
+		main     string
+		patterns []string
+		want     []string
+		wantErr  bool
+	}{
+		{desc: "imports", main: "index.js", want: []string{"index.js", "b.js", "lazy.mjs", "lib/a.js", "lib/a.wasm"}},
+		{desc: "imports of nested main", main: "lib/a.js", want: []string{"lib/a.js", "b.js", "lib/a.wasm"}},
+		{desc: "patterns", main: "index.js", patterns: []string{"*.js", "lib/*.wasm"}, want: []string{"index.js", "b.js", "lib/a.wasm", "unused.js"}},
+		{desc: "invalid pattern", main: "index.js", patterns: []string{"["}, wantErr: true},
+		{desc: "missing main", main: "main.js", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		modules, err := FindWorkerModules(dir, tt.main, tt.patterns)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: FindWorkerModules() error = %v, wantErr %v", tt.desc, err, tt.wantErr)
+
+			continue
+		}
+
+		var got []string
+
+		for _, m := range modules {
+			got = append(got, m.Name)
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: FindWorkerModules() = %v, want %v", tt.desc, got, tt.want)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package cf
 import (
 	"context"
 	"encoding/hex"
+	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/cloudflare/cloudflare-go"
 	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
@@ -19,8 +21,16 @@ type WorkerScript struct {
 	Name    fields.StringInputField `state:"force_new"`
 	Hash    fields.StringInputField
 	EnvVars fields.MapInputField
+	// KVNamespaces map binding names to IDs of bound KV namespaces.
+	KVNamespaces fields.MapInputField
+
+	ETag fields.StringOutputField
 
 	Path string `state:"-"`
+	// MainModule is set for ES module workers, which are uploaded along with all Modules instead of Path.
+	// Service worker format workers use Modules as blobs bound to them.
+	MainModule string          `state:"-"`
+	Modules    []*WorkerModule `state:"-"`
 }
 
 func (o *WorkerScript) ReferenceID() string {
@@ -47,8 +57,21 @@ func (o *WorkerScript) Read(ctx context.Context, meta interface{}) error {
 
 	o.MarkAsExisting()
 
-	sum := blake3.Sum256([]byte(workerRes.WorkerScript.Script))
-	o.Hash.SetCurrent(hex.EncodeToString(sum[:])[:32])
+	if o.MainModule == "" && len(o.Modules) == 0 {
+		sum := blake3.Sum256([]byte(workerRes.WorkerScript.Script))
+		o.Hash.SetCurrent(hex.EncodeToString(sum[:])[:32])
+	} else {
+		// Workers with multiple parts are downloaded as multipart form, so their hash is tracked in state
+		// and verified with etag of script instead.
+		etag, err := o.remoteETag(ctx, pctx)
+		if err != nil {
+			return err
+		}
+
+		if o.ETag.Current() != "" && o.ETag.Current() != etag {
+			o.Hash.SetCurrent("")
+		}
+	}
 
 	bindings, err := cli.ListWorkerBindings(ctx, &cloudflare.WorkerRequestParams{
 		ZoneID:     o.ZoneID.Any(),
@@ -59,21 +82,112 @@ func (o *WorkerScript) Read(ctx context.Context, meta interface{}) error {
 	}
 
 	envVars := make(map[string]interface{})
+	kvNamespaces := make(map[string]interface{})
 
 	for _, b := range bindings.BindingList {
-		if b.Binding.Type() != cloudflare.WorkerSecretTextBindingType {
-			continue
+		switch b.Binding.Type() {
+		case cloudflare.WorkerSecretTextBindingType:
+			envVars[b.Name] = b.Binding.(cloudflare.WorkerSecretTextBinding).Text
+		case cloudflare.WorkerKvNamespaceBindingType:
+			kvNamespaces[b.Name] = b.Binding.(cloudflare.WorkerKvNamespaceBinding).NamespaceID
 		}
-
-		envVars[b.Name] = b.Binding.(cloudflare.WorkerSecretTextBinding).Text
 	}
 
 	o.EnvVars.SetCurrent(envVars)
+	o.KVNamespaces.SetCurrent(kvNamespaces)
+
+	return nil
+}
+
+func (o *WorkerScript) remoteETag(ctx context.Context, pctx *config.PluginContext) (string, error) {
+	cli := pctx.CloudflareClient()
+
+	scripts, err := pctx.FuncCache("WorkerScripts:list", func() (interface{}, error) {
+		return cli.ListWorkerScripts(ctx)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error listing worker scripts: %w", err)
+	}
+
+	for _, s := range scripts.(cloudflare.WorkerListResponse).WorkerList { //nolint: gocritic
+		if s.ID == o.Name.Any() {
+			return s.ETAG, nil
+		}
+	}
+
+	return "", nil
+}
+
+// uploadWorkerParts uploads worker as multipart upload with all its modules, or blobs bound to service worker format worker.
+func (o *WorkerScript) uploadWorkerParts(ctx context.Context, wranglerCli *config.WranglerCloudflareAPI) error {
+	modules, err := readWorkerModules(o.Modules)
+	if err != nil {
+		return err
+	}
+
+	bindings := make([]map[string]interface{}, 0, len(o.EnvVars.Wanted())+len(o.Modules))
+
+	for k, v := range o.EnvVars.Wanted() {
+		bindings = append(bindings, map[string]interface{}{
+			"type": "secret_text",
+			"name": k,
+			"text": v.(string),
+		})
+	}
+
+	for k, v := range o.KVNamespaces.Wanted() {
+		bindings = append(bindings, map[string]interface{}{
+			"type":         "kv_namespace",
+			"name":         k,
+			"namespace_id": v.(string),
+		})
+	}
+
+	metadata := make(map[string]interface{})
+
+	if o.MainModule != "" {
+		metadata["main_module"] = o.MainModule
+	} else {
+		script, err := os.ReadFile(o.Path)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range o.Modules {
+			bindings = append(bindings, map[string]interface{}{
+				"type": WorkerBlobBindingTypes[filepath.Ext(m.Name)],
+				"name": WorkerBlobBindingName(m.Name),
+				"part": m.Name,
+			})
+		}
+
+		metadata["body_part"] = workerScriptPart
+		modules = append([]*config.WorkerModule{{
+			Name:        workerScriptPart,
+			ContentType: "application/javascript",
+			Content:     script,
+		}}, modules...)
+	}
+
+	metadata["bindings"] = bindings
+
+	etag, err := wranglerCli.UploadWorkerScript(ctx, o.Name.Wanted(), metadata, modules)
+	if err != nil {
+		return err
+	}
+
+	o.ETag.SetCurrent(etag)
 
 	return nil
 }
 
-func (o *WorkerScript) createOrUpdateWorkerScript(ctx context.Context, cli *cloudflare.API) error {
+func (o *WorkerScript) createOrUpdateWorkerScript(ctx context.Context, pctx *config.PluginContext) error {
+	if o.MainModule != "" || len(o.Modules) != 0 {
+		return o.uploadWorkerParts(ctx, pctx.WranglerCloudflareClient())
+	}
+
+	cli := pctx.CloudflareClient()
+
 	scriptContent, err := os.ReadFile(o.Path)
 	if err != nil {
 		return err
@@ -86,28 +200,37 @@ func (o *WorkerScript) createOrUpdateWorkerScript(ctx context.Context, cli *clou
 		}
 	}
 
-	_, err = cli.UploadWorkerWithBindings(ctx, &cloudflare.WorkerRequestParams{
+	for k, v := range o.KVNamespaces.Wanted() {
+		bindings[k] = cloudflare.WorkerKvNamespaceBinding{
+			NamespaceID: v.(string),
+		}
+	}
+
+	res, err := cli.UploadWorkerWithBindings(ctx, &cloudflare.WorkerRequestParams{
 		ScriptName: o.Name.Wanted(),
 	}, &cloudflare.WorkerScriptParams{
 		Script:   string(scriptContent),
 		Bindings: bindings,
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	o.ETag.SetCurrent(res.WorkerScript.ETAG)
+
+	return nil
 }
 
 func (o *WorkerScript) Create(ctx context.Context, meta interface{}) error {
 	pctx := meta.(*config.PluginContext)
-	cli := pctx.CloudflareClient()
 
-	return o.createOrUpdateWorkerScript(ctx, cli)
+	return o.createOrUpdateWorkerScript(ctx, pctx)
 }
 
 func (o *WorkerScript) Update(ctx context.Context, meta interface{}) error {
 	pctx := meta.(*config.PluginContext)
-	cli := pctx.CloudflareClient()
 
-	return o.createOrUpdateWorkerScript(ctx, cli)
+	return o.createOrUpdateWorkerScript(ctx, pctx)
 }
 
 func (o *WorkerScript) Delete(ctx context.Context, meta interface{}) error {
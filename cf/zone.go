@@ -0,0 +1,240 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+)
+
+const (
+	ZoneStatusPending = "pending"
+
+	zoneSettingOn  = "on"
+	zoneSettingOff = "off"
+)
+
+// Zone is created when missing but never deleted, as that would wipe all of its records.
+type Zone struct {
+	registry.ResourceBase
+
+	Name fields.StringInputField `state:"force_new"`
+
+	MinTLSVersion         fields.StringInputField
+	AlwaysUseHTTPS        fields.BoolInputField
+	HSTSEnabled           fields.BoolInputField
+	HSTSMaxAge            fields.IntInputField
+	HSTSIncludeSubdomains fields.BoolInputField
+	HSTSPreload           fields.BoolInputField
+	HSTSNoSniff           fields.BoolInputField
+	HTTP3                 fields.BoolInputField
+	Brotli                fields.BoolInputField
+
+	ID          fields.StringOutputField
+	Status      fields.StringOutputField
+	NameServers fields.StringOutputField
+}
+
+func (o *Zone) ReferenceID() string {
+	return fields.GenerateID("zones/%s", o.Name)
+}
+
+// GetName includes nameservers of zones pending activation, so that they are shown in plan.
+func (o *Zone) GetName() string {
+	name := fields.VerboseString(o.Name)
+
+	if !o.IsExisting() {
+		return fmt.Sprintf("%s (nameservers are assigned on creation)", name)
+	}
+
+	if o.Status.Current() == ZoneStatusPending && o.NameServers.Current() != "" {
+		return fmt.Sprintf("%s (pending, nameservers: %s)", name, o.NameServers.Current())
+	}
+
+	return name
+}
+
+func (o *Zone) setZone(z *cloudflare.Zone) {
+	o.ID.SetCurrent(z.ID)
+	o.Status.SetCurrent(z.Status)
+	o.NameServers.SetCurrent(strings.Join(z.NameServers, ", "))
+}
+
+func (o *Zone) Read(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+
+	zones, err := cli.ListZones(ctx, o.Name.Any())
+	if err != nil {
+		return fmt.Errorf("error fetching zone: %w", err)
+	}
+
+	if len(zones) == 0 {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	o.MarkAsExisting()
+	o.Name.SetCurrent(zones[0].Name)
+	o.setZone(&zones[0])
+
+	return o.readSettings(ctx, cli)
+}
+
+func (o *Zone) hasSettings() bool {
+	return len(o.settings()) != 0
+}
+
+func (o *Zone) readSettings(ctx context.Context, cli *cloudflare.API) error {
+	if !o.hasSettings() {
+		return nil
+	}
+
+	res, err := cli.ZoneSettings(ctx, o.ID.Current())
+	if err != nil {
+		return fmt.Errorf("error fetching zone settings: %w", err)
+	}
+
+	for _, s := range res.Result {
+		switch s.ID {
+		case "min_tls_version":
+			setStringIfWanted(o.MinTLSVersion, s.Value)
+		case "always_use_https":
+			setOnOffIfWanted(o.AlwaysUseHTTPS, s.Value)
+		case "http3":
+			setOnOffIfWanted(o.HTTP3, s.Value)
+		case "brotli":
+			setOnOffIfWanted(o.Brotli, s.Value)
+		case "security_header":
+			o.readHSTS(s.Value)
+		}
+	}
+
+	return nil
+}
+
+func setStringIfWanted(f fields.StringInputField, v interface{}) {
+	if _, ok := f.LookupWanted(); ok {
+		f.SetCurrent(fmt.Sprint(v))
+	}
+}
+
+func setOnOffIfWanted(f fields.BoolInputField, v interface{}) {
+	if _, ok := f.LookupWanted(); ok {
+		f.SetCurrent(v == zoneSettingOn)
+	}
+}
+
+func (o *Zone) readHSTS(v interface{}) {
+	if _, ok := o.HSTSEnabled.LookupWanted(); !ok {
+		return
+	}
+
+	m, _ := v.(map[string]interface{})
+	hsts, _ := m["strict_transport_security"].(map[string]interface{})
+
+	enabled, _ := hsts["enabled"].(bool)
+	maxAge, _ := hsts["max_age"].(float64)
+	includeSubdomains, _ := hsts["include_subdomains"].(bool)
+	preload, _ := hsts["preload"].(bool)
+	nosniff, _ := hsts["nosniff"].(bool)
+
+	o.HSTSEnabled.SetCurrent(enabled)
+	o.HSTSMaxAge.SetCurrent(int(maxAge))
+	o.HSTSIncludeSubdomains.SetCurrent(includeSubdomains)
+	o.HSTSPreload.SetCurrent(preload)
+	o.HSTSNoSniff.SetCurrent(nosniff)
+}
+
+func onOff(v bool) string {
+	if v {
+		return zoneSettingOn
+	}
+
+	return zoneSettingOff
+}
+
+func (o *Zone) settings() []cloudflare.ZoneSetting {
+	var ret []cloudflare.ZoneSetting
+
+	if v, ok := o.MinTLSVersion.LookupWanted(); ok {
+		ret = append(ret, cloudflare.ZoneSetting{ID: "min_tls_version", Value: v})
+	}
+
+	if v, ok := o.AlwaysUseHTTPS.LookupWanted(); ok {
+		ret = append(ret, cloudflare.ZoneSetting{ID: "always_use_https", Value: onOff(v)})
+	}
+
+	if v, ok := o.HTTP3.LookupWanted(); ok {
+		ret = append(ret, cloudflare.ZoneSetting{ID: "http3", Value: onOff(v)})
+	}
+
+	if v, ok := o.Brotli.LookupWanted(); ok {
+		ret = append(ret, cloudflare.ZoneSetting{ID: "brotli", Value: onOff(v)})
+	}
+
+	if v, ok := o.HSTSEnabled.LookupWanted(); ok {
+		ret = append(ret, cloudflare.ZoneSetting{
+			ID: "security_header",
+			Value: map[string]interface{}{
+				"strict_transport_security": map[string]interface{}{
+					"enabled":            v,
+					"max_age":            o.HSTSMaxAge.Wanted(),
+					"include_subdomains": o.HSTSIncludeSubdomains.Wanted(),
+					"preload":            o.HSTSPreload.Wanted(),
+					"nosniff":            o.HSTSNoSniff.Wanted(),
+				},
+			},
+		})
+	}
+
+	return ret
+}
+
+func (o *Zone) updateSettings(ctx context.Context, cli *cloudflare.API) error {
+	settings := o.settings()
+	if len(settings) == 0 {
+		return nil
+	}
+
+	_, err := cli.UpdateZoneSettings(ctx, o.ID.Current(), settings)
+	if err != nil {
+		return fmt.Errorf("error updating zone '%s' settings: %w", o.Name.Wanted(), err)
+	}
+
+	return nil
+}
+
+func (o *Zone) Create(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+
+	if cli.AccountID == "" {
+		return fmt.Errorf("$CLOUDFLARE_ACCOUNT_ID or secrets.cloudflare_account_id is required to create zone '%s'", o.Name.Wanted())
+	}
+
+	zone, err := cli.CreateZone(ctx, o.Name.Wanted(), false, cloudflare.Account{ID: cli.AccountID}, "full")
+	if err != nil {
+		return fmt.Errorf("error creating zone '%s': %w", o.Name.Wanted(), err)
+	}
+
+	o.setZone(&zone)
+
+	return o.updateSettings(ctx, cli)
+}
+
+func (o *Zone) Update(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+
+	return o.updateSettings(ctx, cli)
+}
+
+func (o *Zone) Delete(ctx context.Context, meta interface{}) error {
+	return nil
+}
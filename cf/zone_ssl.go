@@ -0,0 +1,108 @@
+package cf
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+)
+
+const (
+	ZoneSSLModeOff      = "off"
+	ZoneSSLModeFlexible = "flexible"
+	ZoneSSLModeFull     = "full"
+	ZoneSSLModeStrict   = "strict"
+)
+
+// ZoneSSLSetting manages SSL mode of a zone. Mode that was set before is restored when setting is no longer managed.
+type ZoneSSLSetting struct {
+	registry.ResourceBase
+
+	Zone   fields.StringInputField `state:"force_new"`
+	ZoneID fields.StringInputField
+	Mode   fields.StringInputField
+
+	OriginalMode fields.StringOutputField
+}
+
+func (o *ZoneSSLSetting) ReferenceID() string {
+	return fields.GenerateID("zones/%s/settings/ssl", o.Zone)
+}
+
+func (o *ZoneSSLSetting) GetName() string {
+	return fields.VerboseString(o.Zone)
+}
+
+func (o *ZoneSSLSetting) Read(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+	zoneID := o.ZoneID.Any()
+
+	if zoneID == "" {
+		o.MarkAsNew()
+
+		return nil
+	}
+
+	s, err := cli.ZoneSSLSettings(ctx, zoneID)
+	if err != nil {
+		return fmt.Errorf("error fetching zone ssl settings: %w", err)
+	}
+
+	o.MarkAsExisting()
+	o.Zone.SetCurrent(o.Zone.Any())
+	o.ZoneID.SetCurrent(zoneID)
+	o.Mode.SetCurrent(s.Value)
+
+	if o.OriginalMode.Current() == "" {
+		o.OriginalMode.SetCurrent(s.Value)
+	}
+
+	return nil
+}
+
+func (o *ZoneSSLSetting) Create(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+	zoneID := o.ZoneID.Wanted()
+
+	s, err := cli.ZoneSSLSettings(ctx, zoneID)
+	if err != nil {
+		return fmt.Errorf("error fetching zone ssl settings: %w", err)
+	}
+
+	o.OriginalMode.SetCurrent(s.Value)
+
+	return o.Update(ctx, meta)
+}
+
+func (o *ZoneSSLSetting) Update(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+
+	_, err := cli.UpdateZoneSSLSettings(ctx, o.ZoneID.Wanted(), o.Mode.Wanted())
+	if err != nil {
+		return fmt.Errorf("error updating zone '%s' ssl mode: %w", o.Zone.Wanted(), err)
+	}
+
+	return nil
+}
+
+func (o *ZoneSSLSetting) Delete(ctx context.Context, meta interface{}) error {
+	pctx := meta.(*config.PluginContext)
+	cli := pctx.CloudflareClient()
+
+	mode := o.OriginalMode.Current()
+	if mode == "" || mode == o.Mode.Current() {
+		return nil
+	}
+
+	_, err := cli.UpdateZoneSSLSettings(ctx, o.ZoneID.Current(), mode)
+	if err != nil {
+		return fmt.Errorf("error restoring zone '%s' ssl mode: %w", o.Zone.Current(), err)
+	}
+
+	return nil
+}
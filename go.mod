@@ -5,8 +5,10 @@ go 1.19
 require (
 	github.com/cloudflare/cloudflare-go v0.49.1-0.20220906224447-f4153a58a61b
 	github.com/gorilla/websocket v1.5.0
+	github.com/mitchellh/mapstructure v1.5.0
 	github.com/outblocks/outblocks-plugin-go v0.0.0-20220914114257-711958f591f7
 	github.com/zeebo/blake3 v0.2.3
+	golang.org/x/net v0.0.0-20220826154423-83b083e8dc8b
 	google.golang.org/protobuf v1.28.1
 )
 
@@ -20,9 +22,7 @@ require (
 	github.com/klauspost/cpuid/v2 v2.0.12 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.16 // indirect
-	github.com/mitchellh/mapstructure v1.5.0 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
-	golang.org/x/net v0.0.0-20220826154423-83b083e8dc8b // indirect
 	golang.org/x/sync v0.0.0-20220819030929-7fc1605a5dde // indirect
 	golang.org/x/sys v0.0.0-20220825204002-c680a09ffe64 // indirect
 	golang.org/x/text v0.3.7 // indirect
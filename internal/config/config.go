@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/cloudflare/cloudflare-go"
@@ -35,11 +36,13 @@ func NewCloudflareClient(apiKey, apiEmail, apiToken, apiUserServiceKey, accountI
 		accountID = os.Getenv("CLOUDFLARE_ACCOUNT_ID")
 	}
 
+	httpClient := &http.Client{}
+
 	switch {
 	case apiToken != "":
-		cli, err = cloudflare.NewWithAPIToken(apiToken)
+		cli, err = cloudflare.NewWithAPIToken(apiToken, cloudflare.HTTPClient(httpClient))
 	case apiKey != "" && apiEmail != "":
-		cli, err = cloudflare.New(apiKey, apiEmail)
+		cli, err = cloudflare.New(apiKey, apiEmail, cloudflare.HTTPClient(httpClient))
 	default:
 		return nil, nil, errCredentialsMissing
 	}
@@ -51,7 +54,7 @@ func NewCloudflareClient(apiKey, apiEmail, apiToken, apiUserServiceKey, accountI
 	cli.AccountID = accountID
 	cli.APIUserServiceKey = apiUserServiceKey
 
-	wranglerCli = NewWranglerCloudflareAPI(cli)
+	wranglerCli = NewWranglerCloudflareAPI(cli, httpClient)
 
 	return cli, wranglerCli, nil
 }
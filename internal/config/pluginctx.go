@@ -1,10 +1,12 @@
 package config
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/cloudflare/cloudflare-go"
 	"github.com/outblocks/outblocks-plugin-go/env"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
 )
 
 type funcCacheData struct {
@@ -18,20 +20,31 @@ type PluginContext struct {
 	wranglerCli *WranglerCloudflareAPI
 	settings    *Settings
 
-	funcCache map[string]*funcCacheData
+	funcCache         map[string]*funcCacheData
+	wantedDNSRecords  map[string]struct{}
+	stateDNSRecords   map[string]struct{}
+	unownedDNSRecords map[string]error
+	revokedCerts      []string
+	applyCallback     func(*apiv1.ApplyAction)
 
 	mu struct {
-		funcCache sync.Mutex
+		funcCache     sync.Mutex
+		dnsRecords    sync.Mutex
+		revokedCerts  sync.Mutex
+		applyCallback sync.Mutex
 	}
 }
 
 func NewPluginContext(e env.Enver, cli *cloudflare.API, wranglerCli *WranglerCloudflareAPI, settings *Settings) *PluginContext {
 	return &PluginContext{
-		env:         e,
-		cli:         cli,
-		wranglerCli: wranglerCli,
-		settings:    settings,
-		funcCache:   make(map[string]*funcCacheData),
+		env:               e,
+		cli:               cli,
+		wranglerCli:       wranglerCli,
+		settings:          settings,
+		funcCache:         make(map[string]*funcCacheData),
+		wantedDNSRecords:  make(map[string]struct{}),
+		stateDNSRecords:   make(map[string]struct{}),
+		unownedDNSRecords: make(map[string]error),
 	}
 }
 
@@ -65,3 +78,93 @@ func (c *PluginContext) FuncCache(key string, f func() (interface{}, error)) (in
 
 	return cache.ret, cache.err
 }
+
+func (c *PluginContext) AddWantedDNSRecord(zoneID, key string) {
+	c.mu.dnsRecords.Lock()
+	c.wantedDNSRecords[zoneID+"/"+key] = struct{}{}
+	c.mu.dnsRecords.Unlock()
+}
+
+func (c *PluginContext) IsDNSRecordWanted(zoneID, key string) bool {
+	c.mu.dnsRecords.Lock()
+	_, ok := c.wantedDNSRecords[zoneID+"/"+key]
+	c.mu.dnsRecords.Unlock()
+
+	return ok
+}
+
+// AddStateDNSRecord marks record ID as managed according to state, even if under a different identity.
+func (c *PluginContext) AddStateDNSRecord(zoneID, id string) {
+	c.mu.dnsRecords.Lock()
+	c.stateDNSRecords[zoneID+"/"+id] = struct{}{}
+	c.mu.dnsRecords.Unlock()
+}
+
+// AddUnownedDNSRecord defers ownership error of record until all records were read,
+// as the record may turn out to be managed in state under a different identity.
+func (c *PluginContext) AddUnownedDNSRecord(zoneID, id string, err error) {
+	c.mu.dnsRecords.Lock()
+	c.unownedDNSRecords[zoneID+"/"+id] = err
+	c.mu.dnsRecords.Unlock()
+}
+
+// CheckUnownedDNSRecords returns ownership error of first record that is not managed according to state.
+func (c *PluginContext) CheckUnownedDNSRecords() error {
+	c.mu.dnsRecords.Lock()
+	defer c.mu.dnsRecords.Unlock()
+
+	keys := make([]string, 0, len(c.unownedDNSRecords))
+
+	for k := range c.unownedDNSRecords {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var err error
+
+	for _, k := range keys {
+		if _, ok := c.stateDNSRecords[k]; !ok && err == nil {
+			err = c.unownedDNSRecords[k]
+		}
+	}
+
+	c.unownedDNSRecords = make(map[string]error)
+
+	return err
+}
+
+// QueueOriginCertificateRevocation schedules revocation of replaced certificate, to be done once all plugins stopped using it.
+func (c *PluginContext) QueueOriginCertificateRevocation(id string) {
+	c.mu.revokedCerts.Lock()
+	c.revokedCerts = append(c.revokedCerts, id)
+	c.mu.revokedCerts.Unlock()
+}
+
+func (c *PluginContext) QueuedOriginCertificateRevocations() []string {
+	c.mu.revokedCerts.Lock()
+	ret := append([]string(nil), c.revokedCerts...)
+	c.mu.revokedCerts.Unlock()
+
+	return ret
+}
+
+// SetApplyCallback sets callback that apply progress is reported to. Returned callback is safe for concurrent use
+// and should be passed to registry, so that resources can report their own progress along with it.
+func (c *PluginContext) SetApplyCallback(f func(*apiv1.ApplyAction)) func(*apiv1.ApplyAction) {
+	c.mu.applyCallback.Lock()
+	c.applyCallback = f
+	c.mu.applyCallback.Unlock()
+
+	return c.ReportApplyProgress
+}
+
+// ReportApplyProgress passes progress of long running action to apply callback, if any is set.
+func (c *PluginContext) ReportApplyProgress(a *apiv1.ApplyAction) {
+	c.mu.applyCallback.Lock()
+	defer c.mu.applyCallback.Unlock()
+
+	if c.applyCallback != nil {
+		c.applyCallback(a)
+	}
+}
@@ -5,19 +5,27 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
 
 	"github.com/cloudflare/cloudflare-go"
 )
 
 type WranglerCloudflareAPI struct {
-	api *cloudflare.API
+	api        *cloudflare.API
+	httpClient *http.Client
 }
 
-func NewWranglerCloudflareAPI(api *cloudflare.API) *WranglerCloudflareAPI {
+// NewWranglerCloudflareAPI creates API client, httpClient has to be the one api was configured with
+// as it is used for requests that cloudflare client cannot make.
+func NewWranglerCloudflareAPI(api *cloudflare.API, httpClient *http.Client) *WranglerCloudflareAPI {
 	return &WranglerCloudflareAPI{
-		api: api,
+		api:        api,
+		httpClient: httpClient,
 	}
 }
 
@@ -43,54 +51,372 @@ func (a *WranglerCloudflareAPI) CreatePagesProject(ctx context.Context, name str
 	return r, nil
 }
 
-func (a *WranglerCloudflareAPI) CreatePagesDeployment(ctx context.Context, name string, manifest map[string]string) error {
+type PagesProjectEnvVar struct {
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value"`
+}
+
+// PagesProjectBinding references bound resource, only the field matching binding type is set.
+type PagesProjectBinding struct {
+	ID          string `json:"id,omitempty"`
+	NamespaceID string `json:"namespace_id,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+type PagesProjectBuildConfig struct {
+	BuildCommand   string `json:"build_command"`
+	DestinationDir string `json:"destination_dir"`
+	RootDir        string `json:"root_dir"`
+}
+
+type PagesProjectDeploymentConfig struct {
+	CompatibilityDate string `json:"compatibility_date,omitempty"`
+	// EnvVars set to nil are removed.
+	EnvVars map[string]*PagesProjectEnvVar `json:"env_vars"`
+
+	KVNamespaces            map[string]*PagesProjectBinding `json:"kv_namespaces"`
+	R2Buckets               map[string]*PagesProjectBinding `json:"r2_buckets"`
+	D1Databases             map[string]*PagesProjectBinding `json:"d1_databases"`
+	DurableObjectNamespaces map[string]*PagesProjectBinding `json:"durable_object_namespaces"`
+}
+
+type PagesProjectDeploymentConfigs struct {
+	Production PagesProjectDeploymentConfig `json:"production"`
+	Preview    PagesProjectDeploymentConfig `json:"preview"`
+}
+
+type PagesDeploymentStage struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+type PagesDeploymentDetails struct {
+	ID          string               `json:"id"`
+	URL         string               `json:"url"`
+	Environment string               `json:"environment"`
+	LatestStage PagesDeploymentStage `json:"latest_stage"`
+}
+
+type PagesDomainValidation struct {
+	Status       string `json:"status"`
+	Method       string `json:"method"`
+	TXTName      string `json:"txt_name"`
+	TXTValue     string `json:"txt_value"`
+	ErrorMessage string `json:"error_message"`
+}
+
+type PagesDomainDetails struct {
+	Name           string                `json:"name"`
+	Status         string                `json:"status"`
+	ValidationData PagesDomainValidation `json:"validation_data"`
+}
+
+type PagesProjectDetails struct {
+	Name              string                        `json:"name"`
+	SubDomain         string                        `json:"subdomain"`
+	Domains           []string                      `json:"domains"`
+	ProductionBranch  string                        `json:"production_branch"`
+	BuildConfig       PagesProjectBuildConfig       `json:"build_config"`
+	DeploymentConfigs PagesProjectDeploymentConfigs `json:"deployment_configs"`
+
+	// CanonicalDeployment is deployment currently live in production.
+	CanonicalDeployment *PagesDeploymentDetails `json:"canonical_deployment"`
+}
+
+func (a *WranglerCloudflareAPI) PagesProject(ctx context.Context, name string) (*PagesProjectDetails, error) {
+	uri := fmt.Sprintf("/accounts/%s/pages/projects/%s", a.api.AccountID, name)
+	r := &PagesProjectDetails{}
+
+	res, err := a.api.Raw(ctx, "GET", uri, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(res, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// UpdatePagesProject patches project settings, only keys present in body are changed.
+func (a *WranglerCloudflareAPI) UpdatePagesProject(ctx context.Context, name string, body map[string]interface{}) error {
+	uri := fmt.Sprintf("/accounts/%s/pages/projects/%s", a.api.AccountID, name)
+
+	_, err := a.api.Raw(ctx, "PATCH", uri, body, nil)
+
+	return err
+}
+
+// CreatePagesDeployment creates production deployment, or preview one when branch differs from production branch.
+// Files are special files (e.g. _headers, _redirects) sent along with manifest.
+func (a *WranglerCloudflareAPI) CreatePagesDeployment(ctx context.Context, name, branch string, manifest, files map[string]string) (*PagesDeploymentDetails, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
 	part, err := writer.CreateFormField("manifest")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	manifestBytes, err := json.Marshal(manifest)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	_, err = part.Write(manifestBytes)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if branch != "" {
+		err = writer.WriteField("branch", branch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for f, content := range files {
+		part, err = writer.CreateFormFile(f, f)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = part.Write([]byte(content))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	err = writer.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	headers := make(http.Header)
 	headers.Set("Content-Type", writer.FormDataContentType())
 
-	_, err = a.api.Raw(ctx, "POST",
+	res, err := a.api.Raw(ctx, "POST",
 		fmt.Sprintf("/accounts/%s/pages/projects/%s/deployments", a.api.AccountID, name), body,
 		headers,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &PagesDeploymentDetails{}
+
+	err = json.Unmarshal(res, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (a *WranglerCloudflareAPI) PagesDeployment(ctx context.Context, name, id string) (*PagesDeploymentDetails, error) {
+	uri := fmt.Sprintf("/accounts/%s/pages/projects/%s/deployments/%s", a.api.AccountID, name, id)
+	r := &PagesDeploymentDetails{}
+
+	res, err := a.api.Raw(ctx, "GET", uri, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(res, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (a *WranglerCloudflareAPI) PagesDomains(ctx context.Context, name string) ([]*PagesDomainDetails, error) {
+	uri := fmt.Sprintf("/accounts/%s/pages/projects/%s/domains", a.api.AccountID, name)
+
+	var r []*PagesDomainDetails
+
+	res, err := a.api.Raw(ctx, "GET", uri, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(res, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// RollbackPagesDeployment makes previous production deployment live again.
+func (a *WranglerCloudflareAPI) RollbackPagesDeployment(ctx context.Context, name, id string) (*PagesDeploymentDetails, error) {
+	uri := fmt.Sprintf("/accounts/%s/pages/projects/%s/deployments/%s/rollback", a.api.AccountID, name, id)
+	r := &PagesDeploymentDetails{}
+
+	res, err := a.api.Raw(ctx, "POST", uri, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(res, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+type WorkerModule struct {
+	Name        string
+	ContentType string
+	Content     []byte
+}
+
+var multipartQuoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// UploadWorkerScript uploads worker as multipart upload, with each module (or blob used by bindings) sent as a separate part,
+// and returns etag of uploaded script. Metadata points to main module (or body part) and defines bindings.
+func (a *WranglerCloudflareAPI) UploadWorkerScript(ctx context.Context, name string, metadata map[string]interface{}, modules []*WorkerModule) (string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	metadataBytes, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+
+	err = writer.WriteField("metadata", string(metadataBytes))
+	if err != nil {
+		return "", err
+	}
+
+	for _, m := range modules {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, multipartQuoteEscaper.Replace(m.Name), multipartQuoteEscaper.Replace(m.Name)))
+		h.Set("Content-Type", m.ContentType)
+
+		part, err := writer.CreatePart(h)
+		if err != nil {
+			return "", err
+		}
+
+		_, err = part.Write(m.Content)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return "", err
+	}
+
+	headers := make(http.Header)
+	headers.Set("Content-Type", writer.FormDataContentType())
+
+	res, err := a.api.Raw(ctx, "PUT",
+		fmt.Sprintf("/accounts/%s/workers/scripts/%s", a.api.AccountID, name), body,
+		headers,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	var script struct {
+		ETag string `json:"etag"`
+	}
+
+	err = json.Unmarshal(res, &script)
+	if err != nil {
+		return "", fmt.Errorf("error unmarshaling worker upload response: %w", err)
+	}
+
+	return script.ETag, nil
+}
+
+type KVNamespace struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+const kvNamespacesPerPage = 100
+
+func (a *WranglerCloudflareAPI) KVNamespaces(ctx context.Context) ([]*KVNamespace, error) {
+	var ret []*KVNamespace
+
+	for page := 1; ; page++ {
+		uri := fmt.Sprintf("/accounts/%s/storage/kv/namespaces?per_page=%d&page=%d", a.api.AccountID, kvNamespacesPerPage, page)
+
+		res, err := a.api.Raw(ctx, "GET", uri, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var r []*KVNamespace
+
+		err = json.Unmarshal(res, &r)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, r...)
+
+		if len(r) < kvNamespacesPerPage {
+			return ret, nil
+		}
+	}
+}
+
+func (a *WranglerCloudflareAPI) CreateKVNamespace(ctx context.Context, title string) (*KVNamespace, error) {
+	uri := fmt.Sprintf("/accounts/%s/storage/kv/namespaces", a.api.AccountID)
+	r := &KVNamespace{}
+
+	res, err := a.api.Raw(ctx, "POST", uri, map[string]string{"title": title}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(res, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (a *WranglerCloudflareAPI) RenameKVNamespace(ctx context.Context, id, title string) error {
+	uri := fmt.Sprintf("/accounts/%s/storage/kv/namespaces/%s", a.api.AccountID, id)
+
+	_, err := a.api.Raw(ctx, "PUT", uri, map[string]string{"title": title}, nil)
+
+	return err
+}
+
+func (a *WranglerCloudflareAPI) DeleteKVNamespace(ctx context.Context, id string) error {
+	uri := fmt.Sprintf("/accounts/%s/storage/kv/namespaces/%s", a.api.AccountID, id)
+
+	_, err := a.api.Raw(ctx, "DELETE", uri, nil, nil)
 
 	return err
 }
 
 func (a *WranglerCloudflareAPI) PagesAPI(name string) *WranglerCloudflarePagesAPI {
 	return &WranglerCloudflarePagesAPI{
-		api:  a.api,
-		name: name,
+		api:        a.api,
+		httpClient: a.httpClient,
+		name:       name,
 	}
 }
 
 type WranglerCloudflarePagesAPI struct {
-	api  *cloudflare.API
-	name string
+	api        *cloudflare.API
+	httpClient *http.Client
+	name       string
 
-	pagesAPI *cloudflare.API
-	jwt      string
+	jwt string
+	mu  sync.Mutex
 }
 
 func (a *WranglerCloudflarePagesAPI) fetchJWT(ctx context.Context) (string, error) {
@@ -110,29 +436,51 @@ func (a *WranglerCloudflarePagesAPI) fetchJWT(ctx context.Context) (string, erro
 	return r["jwt"], nil
 }
 
-func (a *WranglerCloudflarePagesAPI) rawPagesRequest(ctx context.Context, method, uri string, data interface{}) (json.RawMessage, error) {
-	var err error
+// token returns JWT used to authenticate pages assets requests, fetching it if needed.
+func (a *WranglerCloudflarePagesAPI) token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-	if a.jwt == "" {
-		a.jwt, err = a.fetchJWT(ctx)
-		if err != nil {
-			return nil, err
-		}
+	if a.jwt != "" {
+		return a.jwt, nil
 	}
 
-	if a.pagesAPI == nil {
-		a.pagesAPI, err = cloudflare.NewWithAPIToken(a.jwt)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		a.pagesAPI.APIToken = a.jwt
+	jwt, err := a.fetchJWT(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	ret, err := a.pagesAPI.Raw(ctx, method, uri, data, nil)
+	a.jwt = jwt
 
-	if _, ok := err.(*cloudflare.AuthorizationError); ok {
+	return jwt, nil
+}
+
+// resetToken makes next request fetch a new JWT, e.g. when current one expired.
+func (a *WranglerCloudflarePagesAPI) resetToken(jwt string) {
+	a.mu.Lock()
+
+	if a.jwt == jwt {
 		a.jwt = ""
+	}
+
+	a.mu.Unlock()
+}
+
+func (a *WranglerCloudflarePagesAPI) rawPagesRequest(ctx context.Context, method, uri string, data interface{}) (json.RawMessage, error) {
+	jwt, err := a.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	api, err := cloudflare.NewWithAPIToken(jwt, cloudflare.HTTPClient(a.httpClient))
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := api.Raw(ctx, method, uri, data, nil)
+
+	if _, ok := err.(*cloudflare.AuthorizationError); ok {
+		a.resetToken(jwt)
 
 		return a.rawPagesRequest(ctx, method, uri, data)
 	}
@@ -170,8 +518,110 @@ func (a *WranglerCloudflarePagesAPI) UpsertHashes(ctx context.Context, hashes []
 	return err
 }
 
-func (a *WranglerCloudflarePagesAPI) UploadBucket(ctx context.Context, payload interface{}) error {
-	_, err := a.rawPagesRequest(ctx, "POST", "/pages/assets/upload", payload)
+const pagesUploadResponseMaxSize = 1 << 20
 
-	return err
+// PagesUploadError is returned when pages assets upload failed with non-successful status code.
+type PagesUploadError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *PagesUploadError) Error() string {
+	return fmt.Sprintf("pages assets upload failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+// Retryable returns true if upload failed due to rate limiting, server error or expired token.
+func (e *PagesUploadError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	}
+
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
+// TooLarge returns true if upload failed due to payload being too large.
+func (e *PagesUploadError) TooLarge() bool {
+	return e.StatusCode == http.StatusRequestEntityTooLarge
+}
+
+// UploadBucket uploads assets with JSON payload streamed by writePayload while request is sent,
+// so that payload is never held in memory as a whole. Request is sent only once, retries are up to the caller.
+func (a *WranglerCloudflarePagesAPI) UploadBucket(ctx context.Context, writePayload func(w io.Writer) error) error {
+	jwt, err := a.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	r, w := io.Pipe()
+
+	// Closing pipe reader stops writer if request failed midway.
+	defer r.Close()
+
+	go func() {
+		_ = w.CloseWithError(writePayload(w))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.api.BaseURL+"/pages/assets/upload", r)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", a.api.UserAgent)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		a.resetToken(jwt)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, pagesUploadResponseMaxSize))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return &PagesUploadError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+		}
+	}
+
+	// Successful status alone is not enough, upload result is reported in response envelope.
+	var envelope cloudflare.Response
+
+	err = json.Unmarshal(body, &envelope)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling pages assets upload response: %w", err)
+	}
+
+	if !envelope.Success {
+		return &PagesUploadError{
+			StatusCode: resp.StatusCode,
+			Message:    pagesUploadErrorMessage(envelope.Errors),
+		}
+	}
+
+	return nil
+}
+
+func pagesUploadErrorMessage(errs []cloudflare.ResponseInfo) string {
+	if len(errs) == 0 {
+		return "unknown error"
+	}
+
+	msgs := make([]string, len(errs))
+
+	for i, e := range errs {
+		msgs[i] = fmt.Sprintf("%d: %s", e.Code, e.Message)
+	}
+
+	return strings.Join(msgs, ", ")
 }
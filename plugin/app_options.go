@@ -0,0 +1,103 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+type PagesBuildOptions struct {
+	Command   string `mapstructure:"command"`
+	OutputDir string `mapstructure:"output_dir"`
+	RootDir   string `mapstructure:"root_dir"`
+}
+
+type PagesRedirectOptions struct {
+	From   string `mapstructure:"from"`
+	To     string `mapstructure:"to"`
+	Status int    `mapstructure:"status"`
+}
+
+// PagesBindingsOptions map binding names to: KV namespace ID, R2 bucket name, D1 database ID and Durable Object namespace ID.
+type PagesBindingsOptions struct {
+	KV             map[string]string `mapstructure:"kv"`
+	R2             map[string]string `mapstructure:"r2"`
+	D1             map[string]string `mapstructure:"d1"`
+	DurableObjects map[string]string `mapstructure:"durable_objects"`
+}
+
+// StaticAppOptions are cloudflare specific properties of static app, defined under 'cloudflare' key.
+type StaticAppOptions struct {
+	ProductionBranch  string             `mapstructure:"production_branch"`
+	CompatibilityDate string             `mapstructure:"compatibility_date"`
+	Build             *PagesBuildOptions `mapstructure:"build"`
+	// PreviewEnv overrides app env vars in preview deployments.
+	PreviewEnv map[string]string `mapstructure:"preview_env"`
+	// Secrets map env var names to keys in outblocks secret store.
+	Secrets        map[string]string     `mapstructure:"secrets"`
+	PreviewSecrets map[string]string     `mapstructure:"preview_secrets"`
+	Bindings       *PagesBindingsOptions `mapstructure:"bindings"`
+	// PreviewBindings override bindings in preview deployments.
+	PreviewBindings *PagesBindingsOptions `mapstructure:"preview_bindings"`
+	// ProductionEnv makes all environments share one project, with environments other than this one deployed as previews.
+	ProductionEnv string `mapstructure:"production_env"`
+	// Routing set to 'spa' serves index.html for all paths without matching file.
+	Routing   string                       `mapstructure:"routing"`
+	Headers   map[string]map[string]string `mapstructure:"headers"`
+	Redirects []*PagesRedirectOptions      `mapstructure:"redirects"`
+	// DeploymentID pins production to a previous deployment, e.g. to roll back a bad release.
+	DeploymentID string `mapstructure:"deployment_id"`
+	// KVNamespaces are binding names of KV namespaces created for the app.
+	KVNamespaces []string `mapstructure:"kv_namespaces"`
+	// KVAdopt allows to take over existing KV namespaces with the same title.
+	KVAdopt bool `mapstructure:"kv_adopt"`
+	// DomainsTimeout is how many seconds to wait for custom domains to become active, 0 only checks them once. Defaults to 120.
+	DomainsTimeout *int `mapstructure:"domains_timeout"`
+}
+
+type staticAppProperties struct {
+	Cloudflare StaticAppOptions `mapstructure:"cloudflare"`
+}
+
+func NewStaticAppOptions(props map[string]interface{}) (*StaticAppOptions, error) {
+	o := &staticAppProperties{}
+
+	err := mapstructure.WeakDecode(props, o)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cloudflare properties of static app: %w", err)
+	}
+
+	return &o.Cloudflare, nil
+}
+
+// FunctionAppOptions are cloudflare specific properties of function app, defined under 'cloudflare' key.
+type FunctionAppOptions struct {
+	// Format is either 'modules' or 'service-worker', detected from main script when empty.
+	Format string `mapstructure:"format"`
+	// Main is main script relative to build dir, defaults to index.mjs if it exists or index.js otherwise.
+	Main string `mapstructure:"main"`
+	// Modules are patterns of modules uploaded along with main one (relative to build dir),
+	// modules imported by main one are followed when empty.
+	Modules []string `mapstructure:"modules"`
+	// MaxSizeMB is compressed size limit of worker in megabytes, which depends on workers plan. Defaults to 10 (paid plan).
+	MaxSizeMB int `mapstructure:"max_size_mb"`
+	// KVNamespaces are binding names of KV namespaces created for the app.
+	KVNamespaces []string `mapstructure:"kv_namespaces"`
+	// KVAdopt allows to take over existing KV namespaces with the same title.
+	KVAdopt bool `mapstructure:"kv_adopt"`
+}
+
+type functionAppProperties struct {
+	Cloudflare FunctionAppOptions `mapstructure:"cloudflare"`
+}
+
+func NewFunctionAppOptions(props map[string]interface{}) (*FunctionAppOptions, error) {
+	o := &functionAppProperties{}
+
+	err := mapstructure.WeakDecode(props, o)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cloudflare properties of function app: %w", err)
+	}
+
+	return &o.Cloudflare, nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"sort"
 	"strings"
 
 	"github.com/outblocks/cli-plugin-cloudflare/cf"
@@ -13,60 +14,29 @@ import (
 	"github.com/outblocks/outblocks-plugin-go/registry"
 	"github.com/outblocks/outblocks-plugin-go/registry/fields"
 	"github.com/outblocks/outblocks-plugin-go/types"
+	"github.com/outblocks/outblocks-plugin-go/util/errgroup"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
-	certPendingValue = "pending"
+	certPendingValue         = "pending"
+	originCertRevokeStateKey = "origin_cert_revoke"
 
 	AppTypeStatic   = "static"
 	AppTypeFunction = "function"
-)
-
-func (p *Plugin) computeZoneMap(state *apiv1.PluginState, domains []*apiv1.DomainInfo) error {
-	var err error
-
-	if state.Other == nil {
-		state.Other = make(map[string][]byte)
-	}
-
-	_ = json.Unmarshal(state.Other["zone_id_map"], &p.zoneMap)
-
-	for _, domainInfo := range domains {
-		for _, d := range domainInfo.Domains {
-			zone := getDomainZoneName(d)
-			if zone == "" {
-				continue
-			}
-
-			id := p.zoneMap[zone]
-
-			if id == "" {
-				id, err = p.cli.ZoneIDByName(zone)
-				if err != nil {
-					return fmt.Errorf("cannot lookup zone '%s': %w", zone, err)
-				}
-			}
-
-			p.zoneMap[zone] = id
-		}
-	}
-
-	data, _ := json.Marshal(p.zoneMap)
-	state.Other["zone_id_map"] = data
 
-	return nil
-}
+	staticAppsWaitConcurrency = 5
+)
 
 func (p *Plugin) isValidCloudflareDomain(domainInfo *apiv1.DomainInfo) bool {
 	if len(domainInfo.Domains) == 0 {
 		return false
 	}
 
-	zone := getDomainZoneName(domainInfo.Domains[0])
+	zone := p.getDomainZoneName(domainInfo.Domains[0])
 
 	for _, d := range domainInfo.Domains[1:] {
-		if zone != getDomainZoneName(d) {
+		if zone != p.getDomainZoneName(d) {
 			return false
 		}
 	}
@@ -96,9 +66,9 @@ func (p *Plugin) validCloudflareDomains(domains []*apiv1.DomainInfo) []*apiv1.Do
 	return ret
 }
 
-func containsNestedSubdomain(domain *apiv1.DomainInfo) bool {
+func (p *Plugin) containsNestedSubdomain(domain *apiv1.DomainInfo) bool {
 	for _, rec := range domain.Domains {
-		if strings.Count(rec, ".") > 2 {
+		if subdomainLevel(rec, p.getDomainZoneName(rec)) > 1 {
 			return true
 		}
 	}
@@ -141,29 +111,58 @@ func (p *Plugin) registerOriginCertificates(reg *registry.Registry, appPlans []*
 		return nil
 	}
 
+	wildcardCerts := make(map[string]*cf.OriginCertificate)
+
 	for _, d := range dom {
-		zone := getDomainZoneName(d.Domains[0])
-		h := make([]fields.Field, len(d.Domains))
+		zone := p.getDomainZoneName(d.Domains[0])
 
-		if containsNestedSubdomain(d) {
-			p.nonOriginDomains = append(p.nonOriginDomains, d)
-			continue
+		opts, err := NewDomainOptions(d)
+		if err != nil {
+			return err
 		}
 
-		for i, v := range d.Domains {
-			h[i] = fields.String(v)
-		}
+		requestType := opts.OriginType
 
-		o := cf.OriginCertificate{
-			Hostnames: fields.Array(h),
+		switch requestType {
+		case "":
+			requestType = cf.OriginCertificateTypeRSA
+		case cf.OriginCertificateTypeRSA, cf.OriginCertificateTypeECC:
+		default:
+			return fmt.Errorf("invalid 'cloudflare_origin_type' of domain '%s', supported values: %s, %s",
+				d.Domains, cf.OriginCertificateTypeRSA, cf.OriginCertificateTypeECC)
 		}
 
-		_, err := reg.RegisterPluginResource(zone, "origin_certificate", &o)
-		if err != nil {
-			return err
+		var o *cf.OriginCertificate
+
+		switch {
+		// Nested subdomains are not covered by zone wildcard, so they always get wildcard certificate of their parent.
+		case opts.OriginWildcard, p.containsNestedSubdomain(d):
+			hostnames := p.wildcardOriginHostnames(d)
+			key := fmt.Sprintf("origin_certificate_wildcard:%s:%s", requestType, strings.Join(hostnames, ","))
+
+			o = wildcardCerts[key]
+			if o != nil {
+				break
+			}
+
+			o = newOriginCertificate(hostnames, requestType, opts.OriginRenewDays)
+			wildcardCerts[key] = o
+
+			_, err = reg.RegisterPluginResource(zone, key, o)
+			if err != nil {
+				return err
+			}
+
+		default:
+			o = newOriginCertificate(d.Domains, requestType, opts.OriginRenewDays)
+
+			_, err = reg.RegisterPluginResource(zone, "origin_certificate", o)
+			if err != nil {
+				return err
+			}
 		}
 
-		p.originCerts[&o] = d
+		p.originCerts[o] = append(p.originCerts[o], d)
 		d.Cert = certPendingValue
 		d.Key = certPendingValue
 
@@ -178,42 +177,150 @@ func (p *Plugin) registerOriginCertificates(reg *registry.Registry, appPlans []*
 	return nil
 }
 
-func (p *Plugin) processOriginCertificates(ctx context.Context, apply bool) error {
-	for cert, domain := range p.originCerts {
-		if !cert.IsExisting() {
+func newOriginCertificate(hostnames []string, requestType string, renewDays int) *cf.OriginCertificate {
+	h := make([]fields.Field, len(hostnames))
+
+	for i, v := range hostnames {
+		h[i] = fields.String(v)
+	}
+
+	return &cf.OriginCertificate{
+		Hostnames:   fields.Array(h),
+		RequestType: fields.String(requestType),
+		RenewDays:   renewDays,
+	}
+}
+
+// wildcardOriginHostnames returns hostnames of certificate covering domain with wildcards, so that a single certificate is shared by all domains of a zone.
+// Names up to first subdomain level are covered by zone and its wildcard, nested ones by wildcard of their parent, e.g. '*.sub.example.com'.
+func (p *Plugin) wildcardOriginHostnames(domain *apiv1.DomainInfo) []string {
+	m := make(map[string]struct{})
+
+	for _, rec := range domain.Domains {
+		rec = strings.ToLower(strings.TrimSuffix(rec, "."))
+		zone := p.getDomainZoneName(rec)
+
+		if subdomainLevel(rec, zone) <= 1 {
+			m[zone] = struct{}{}
+			m["*."+zone] = struct{}{}
+
 			continue
 		}
 
-		domain.Cert = cert.Certificate.Current()
-		domain.Key = cert.PrivateKey.Current()
+		m["*."+rec[strings.Index(rec, ".")+1:]] = struct{}{}
+	}
+
+	ret := make([]string, 0, len(m))
+
+	for h := range m {
+		ret = append(ret, h)
+	}
+
+	sort.Strings(ret)
 
-		if !apply {
+	return ret
+}
+
+func (p *Plugin) processOriginCertificates() {
+	for cert, domains := range p.originCerts {
+		if !cert.IsExisting() {
 			continue
 		}
 
-		zone := getDomainZoneName(domain.Domains[0])
-		zoneID := p.zoneMap[zone]
-		settings, _ := p.cli.ZoneSSLSettings(ctx, zoneID)
+		for _, domain := range domains {
+			// Certificate is going to be reissued.
+			if cert.PendingReplacement() {
+				domain.Cert = certPendingValue
+				domain.Key = certPendingValue
 
-		if settings.Value != "flexible" && settings.Value != "off" {
+				continue
+			}
+
+			domain.Cert = cert.Certificate.Current()
+			domain.Key = cert.PrivateKey.Current()
+		}
+	}
+}
+
+// queueOriginCertificateRevocations stores replaced certificates in state, so that they are revoked once the rest of deploy has applied the new ones.
+func (p *Plugin) queueOriginCertificateRevocations(state *apiv1.PluginState) {
+	ids := p.PluginContext().QueuedOriginCertificateRevocations()
+	if len(ids) == 0 {
+		return
+	}
+
+	if state.Other == nil {
+		state.Other = make(map[string][]byte)
+	}
+
+	var queued []string
+
+	_ = json.Unmarshal(state.Other[originCertRevokeStateKey], &queued)
+	queued = append(queued, ids...)
+
+	state.Other[originCertRevokeStateKey], _ = json.Marshal(queued)
+}
+
+// revokeOriginCertificates revokes replaced certificates, ones that fail to be revoked are kept in state for next deploy.
+func (p *Plugin) revokeOriginCertificates(ctx context.Context, state *apiv1.PluginState) error {
+	var (
+		queued, left []string
+		err          error
+	)
+
+	_ = json.Unmarshal(state.Other[originCertRevokeStateKey], &queued)
+
+	for _, id := range queued {
+		_, revokeErr := p.cli.RevokeOriginCertificate(ctx, id)
+		if revokeErr == nil {
 			continue
 		}
 
-		_, err := p.cli.UpdateZoneSSLSettings(ctx, zoneID, "full")
-		if err != nil {
-			return err
+		// Keep certificate for next run unless it is already gone.
+		if c, getErr := p.cli.OriginCertificate(ctx, id); getErr == nil && c.RevokedAt.IsZero() {
+			left = append(left, id)
+
+			if err == nil {
+				err = fmt.Errorf("error revoking replaced origin certificate '%s': %w", id, revokeErr)
+			}
 		}
 	}
 
-	for _, domain := range p.nonOriginDomains {
-		if domain.Properties.AsMap()["cloudflare_origin"] != true {
-			continue
+	if len(left) == 0 {
+		delete(state.Other, originCertRevokeStateKey)
+	} else {
+		state.Other[originCertRevokeStateKey], _ = json.Marshal(left)
+	}
+
+	return err
+}
+
+func (p *Plugin) waitForStaticApps(ctx context.Context, appStates map[string]*apiv1.AppState) error {
+	g, _ := errgroup.WithConcurrency(ctx, staticAppsWaitConcurrency)
+
+	for _, app := range p.staticApps {
+		app := app
+
+		g.Go(func() error {
+			return app.WaitReady(ctx, p.PluginContext())
+		})
+	}
+
+	err := g.Wait()
+	if err != nil {
+		return err
+	}
+
+	for _, app := range p.staticApps {
+		if !app.ready {
+			p.log.Infof("Cloudflare Pages app '%s' is not ready: %s\n", app.App.Name, app.message)
 		}
 
-		delete(domain.Properties.GetFields(), "cloudflare_origin")
+		if len(app.pendingDomains) != 0 {
+			p.log.Infof("Cloudflare Pages app '%s' custom domains are not active yet:\n%s\n", app.App.Name, strings.Join(app.pendingDomains, "\n"))
+		}
 
-		domain.Cert = ""
-		domain.Key = ""
+		appStates[app.App.Id] = app.AppState()
 	}
 
 	return nil
@@ -235,6 +342,15 @@ func (p *Plugin) processApps(ctx context.Context, reg *registry.Registry, appPla
 
 	appVars := types.AppVarsFromApps(apps)
 
+	kvVars, err := p.registerKVNamespaces(reg, appPlans)
+	if err != nil {
+		return err
+	}
+
+	additionalVars := map[string]interface{}{
+		"cloudflare_kv": kvVars,
+	}
+
 	for _, app := range appPlans {
 		if app.Skip {
 			continue
@@ -260,18 +376,27 @@ func (p *Plugin) processApps(ctx context.Context, reg *registry.Registry, appPla
 				return fmt.Errorf("cannot use url '%s' for cloudflare pages - url has to be a full domain without path", app.State.App.Url)
 			}
 
+			a.KVNamespaces = p.kvNamespaces[app.State.App.Id]
 			p.staticApps[app.State.App.Id] = a
 
-			err = a.process(ctx, p.PluginContext(), reg)
+			// Preview environments never modify shared project, so they do not need secrets.
+			if !a.isPreview(p.PluginContext().Env()) {
+				err = a.resolveSecrets(ctx, p.hostCli)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = a.process(ctx, p.PluginContext(), reg, types.VarsForApp(appVars, app.State.App, additionalVars))
 			if err != nil {
 				return err
 			}
 
 		case app.State.App.Type == AppTypeFunction:
-			domain := getDomainZoneName(hostname)
+			domain := p.getDomainZoneName(hostname)
 
-			if strings.Count(hostname, ".") > 2 {
-				return fmt.Errorf("cannot use domain '%s' for cloudflare worker deployment - current max subdomain level is 1", hostname)
+			if subdomainLevel(hostname, domain) > 1 {
+				return fmt.Errorf("cannot use domain '%s' for cloudflare worker deployment - current max subdomain level is 1 (relative to zone '%s')", hostname, domain)
 			}
 
 			zoneID := p.zoneMap[domain]
@@ -284,9 +409,10 @@ func (p *Plugin) processApps(ctx context.Context, reg *registry.Registry, appPla
 				return err
 			}
 
+			a.KVNamespaces = p.kvNamespaces[app.State.App.Id]
 			p.functionApps[app.State.App.Id] = a
 
-			err = a.process(ctx, p.PluginContext(), reg, types.VarsForApp(appVars, app.State.App, nil))
+			err = a.process(ctx, p.PluginContext(), reg, types.VarsForApp(appVars, app.State.App, additionalVars))
 			if err != nil {
 				return err
 			}
@@ -295,6 +421,7 @@ func (p *Plugin) processApps(ctx context.Context, reg *registry.Registry, appPla
 
 	return nil
 }
+
 func (p *Plugin) processDeployInit(ctx context.Context, reg *registry.Registry, appPlans []*apiv1.AppPlan, state *apiv1.PluginState, domains []*apiv1.DomainInfo, apply bool) ([]*registry.Diff, error) {
 	pctx := p.PluginContext()
 	reg = reg.Partition("init")
@@ -304,7 +431,7 @@ func (p *Plugin) processDeployInit(ctx context.Context, reg *registry.Registry,
 		return nil, err
 	}
 
-	err = p.computeZoneMap(state, domains)
+	err = p.registerZones(ctx, reg, state, domains)
 	if err != nil {
 		return nil, err
 	}
@@ -316,15 +443,26 @@ func (p *Plugin) processDeployInit(ctx context.Context, reg *registry.Registry,
 		return nil, err
 	}
 
+	err = p.registerZoneSSLSettings(reg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.registerOriginPulls(ctx, reg, state, domains)
+	if err != nil {
+		return nil, err
+	}
+
 	// Process registry.
 	diff, err := reg.ProcessAndDiff(ctx, pctx)
 	if err != nil {
 		return nil, err
 	}
 
-	err = p.processOriginCertificates(ctx, false)
+	p.processZones(!apply)
+	p.processOriginCertificates()
 
-	return diff, err
+	return diff, nil
 }
 
 func (p *Plugin) processDeploy(ctx context.Context, reg *registry.Registry, appPlans []*apiv1.AppPlan, state *apiv1.PluginState) (map[string]*apiv1.AppState, []*apiv1.DNSRecord, []*registry.Diff, error) {
@@ -384,6 +522,13 @@ func (p *Plugin) processDeploy(ctx context.Context, reg *registry.Registry, appP
 		return nil, nil, nil, err
 	}
 
+	for _, app := range p.staticApps {
+		if app.PagesDeployment != nil && app.PagesDeployment.DriftedLiveID != "" {
+			p.log.Infof("Cloudflare Pages app '%s' has deployment '%s' made live outside of outblocks, set 'cloudflare.deployment_id' to pin it.\n",
+				app.App.Name, app.PagesDeployment.DriftedLiveID)
+		}
+	}
+
 	return appStates, dnsRecords, diff, err
 }
 
@@ -444,9 +589,21 @@ func (p *Plugin) Apply(r *apiv1.ApplyRequest, reg *registry.Registry, stream api
 		return err
 	}
 
-	err = reg.Apply(ctx, pctx, diff, plugin_go.DefaultRegistryApplyCallback(stream))
+	err = reg.Apply(ctx, pctx, diff, pctx.SetApplyCallback(plugin_go.DefaultRegistryApplyCallback(stream)))
+
+	if r.Priority == 500 {
+		p.processZones(true)
+		p.queueOriginCertificateRevocations(r.State)
+	} else if err == nil {
+		err = p.waitForStaticApps(ctx, appStates)
+
+		// Apps are deployed last, so replaced certificates are no longer in use by any plugin at this point.
+		if err == nil {
+			err = p.revokeOriginCertificates(ctx, r.State)
+		}
+	}
 
-	_ = p.processOriginCertificates(ctx, true)
+	p.processOriginCertificates()
 
 	data, saveErr := reg.Dump()
 	if err == nil {
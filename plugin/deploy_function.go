@@ -21,8 +21,10 @@ type FunctionApp struct {
 	App        *apiv1.App
 	Props      *types.FunctionAppProperties
 	DeployOpts *types.FunctionAppDeployOptions
+	Opts       *FunctionAppOptions
 	ZoneID     string
 
+	KVNamespaces     map[string]*cf.KVNamespace
 	WorkerRoute      *cf.WorkerRoute
 	WorkerScript     *cf.WorkerScript
 	WorkerSchedulers *cf.WorkerSchedulers
@@ -39,14 +41,76 @@ func NewFunctionApp(plan *apiv1.AppPlan, zoneID string) (*FunctionApp, error) {
 		return nil, err
 	}
 
+	cfOpts, err := NewFunctionAppOptions(plan.State.App.Properties.AsMap())
+	if err != nil {
+		return nil, err
+	}
+
 	return &FunctionApp{
 		App:        plan.State.App,
 		Props:      opts,
 		DeployOpts: deployOpts,
+		Opts:       cfOpts,
 		ZoneID:     zoneID,
 	}, nil
 }
 
+// serviceWorkerHash returns hash of script and blobs bound to it, hash of script alone is used if there are no blobs.
+func (o *FunctionApp) serviceWorkerHash(scriptFile string, blobs []*cf.WorkerModule) (string, error) {
+	if len(blobs) != 0 {
+		return cf.WorkerModulesHash(append([]*cf.WorkerModule{{Name: "script", Path: scriptFile}}, blobs...))
+	}
+
+	bytes, err := os.ReadFile(scriptFile)
+	if err != nil {
+		return "", err
+	}
+
+	sum := blake3.Sum256(bytes)
+
+	return hex.EncodeToString(sum[:])[:32], nil
+}
+
+// workerFormat returns main script and format of worker, detecting ES modules by default export if not set explicitly.
+func (o *FunctionApp) workerFormat(buildPath string) (main, format string, err error) {
+	main = o.Opts.Main
+
+	if main == "" {
+		main = "index.js"
+
+		if plugin_util.FileExists(filepath.Join(buildPath, "index.mjs")) {
+			main = "index.mjs"
+		}
+	}
+
+	scriptFile := filepath.Join(buildPath, main)
+	if !plugin_util.FileExists(scriptFile) {
+		return "", "", fmt.Errorf("%s app '%s' is missing %s file in '%s'", o.App.Type, o.App.Name, main, buildPath)
+	}
+
+	format = o.Opts.Format
+
+	switch format {
+	case cf.WorkerFormatModules, cf.WorkerFormatServiceWorker:
+	case "":
+		format = cf.WorkerFormatServiceWorker
+
+		bytes, err := os.ReadFile(scriptFile)
+		if err != nil {
+			return "", "", err
+		}
+
+		if filepath.Ext(main) == ".mjs" || cf.IsWorkerModuleScript(bytes) {
+			format = cf.WorkerFormatModules
+		}
+	default:
+		return "", "", fmt.Errorf("%s app '%s' has invalid format '%s', supported formats: %s, %s",
+			o.App.Type, o.App.Name, format, cf.WorkerFormatModules, cf.WorkerFormatServiceWorker)
+	}
+
+	return main, format, nil
+}
+
 func (o *FunctionApp) process(ctx context.Context, pctx *config.PluginContext, r *registry.Registry, vars map[string]interface{}) error {
 	cli := pctx.CloudflareClient()
 
@@ -58,18 +122,62 @@ func (o *FunctionApp) process(ctx context.Context, pctx *config.PluginContext, r
 		return fmt.Errorf("%s app '%s' build dir '%s' does not exist", o.App.Type, o.App.Name, buildDir)
 	}
 
-	scriptFile := filepath.Join(buildPath, "index.js")
-	if !plugin_util.FileExists(scriptFile) {
-		return fmt.Errorf("%s app '%s' is missing index.js file in '%s'", o.App.Type, o.App.Name, buildPath)
+	main, format, err := o.workerFormat(buildPath)
+	if err != nil {
+		return err
 	}
 
-	bytes, err := os.ReadFile(scriptFile)
+	var (
+		hash    string
+		modules []*cf.WorkerModule
+	)
+
+	scriptFile := filepath.Join(buildPath, main)
+
+	if format == cf.WorkerFormatModules {
+		modules, err = cf.FindWorkerModules(buildPath, main, o.Opts.Modules)
+		if err != nil {
+			return fmt.Errorf("%s app '%s' modules error: %w", o.App.Type, o.App.Name, err)
+		}
+
+		hash, err = cf.WorkerModulesHash(modules)
+		if err != nil {
+			return err
+		}
+	} else {
+		modules, err = cf.FindWorkerBlobs(buildPath)
+		if err != nil {
+			return fmt.Errorf("%s app '%s' bindings error: %w", o.App.Type, o.App.Name, err)
+		}
+
+		hash, err = o.serviceWorkerHash(scriptFile, modules)
+		if err != nil {
+			return err
+		}
+	}
+
+	paths := []string{scriptFile}
+
+	for _, m := range modules {
+		if m.Path != scriptFile {
+			paths = append(paths, m.Path)
+		}
+	}
+
+	size, err := cf.WorkerCompressedSize(paths)
 	if err != nil {
 		return err
 	}
 
-	sum := blake3.Sum256(bytes)
-	hash := hex.EncodeToString(sum[:])[:32]
+	maxSize := int64(cf.WorkerDefaultMaxCompressedSize)
+	if o.Opts.MaxSizeMB > 0 {
+		maxSize = int64(o.Opts.MaxSizeMB) * 1024 * 1024
+	}
+
+	if size > maxSize {
+		return fmt.Errorf("%s app '%s' estimated compressed size is %d bytes, cloudflare workers only support up to %d bytes (set 'cloudflare.max_size_mb' if your plan allows more)",
+			o.App.Type, o.App.Name, size, maxSize)
+	}
 
 	envVars := make(map[string]fields.Field)
 	eval := fields.NewFieldVarEvaluator(vars)
@@ -83,15 +191,28 @@ func (o *FunctionApp) process(ctx context.Context, pctx *config.PluginContext, r
 		envVars[k] = exp
 	}
 
+	kvNamespaces := make(map[string]fields.Field, len(o.KVNamespaces))
+
+	for name, ns := range o.KVNamespaces {
+		kvNamespaces[name] = ns.ID.Input()
+	}
+
 	o.WorkerScript = &cf.WorkerScript{
-		ZoneID:  fields.String(o.ZoneID),
-		Name:    fields.String(scriptName),
-		Hash:    fields.String(hash),
-		EnvVars: fields.Map(envVars),
+		ZoneID:       fields.String(o.ZoneID),
+		Name:         fields.String(scriptName),
+		Hash:         fields.String(hash),
+		EnvVars:      fields.Map(envVars),
+		KVNamespaces: fields.Map(kvNamespaces),
 
 		Path: scriptFile,
 	}
 
+	o.WorkerScript.Modules = modules
+
+	if format == cf.WorkerFormatModules {
+		o.WorkerScript.MainModule = filepath.ToSlash(main)
+	}
+
 	_, err = r.RegisterAppResource(o.App, "worker_script", o.WorkerScript)
 	if err != nil {
 		return err
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"time"
 
 	"github.com/outblocks/cli-plugin-cloudflare/cf"
 	"github.com/outblocks/cli-plugin-cloudflare/internal/config"
+	"github.com/outblocks/outblocks-plugin-go/env"
 	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
 	"github.com/outblocks/outblocks-plugin-go/registry"
 	"github.com/outblocks/outblocks-plugin-go/registry/fields"
@@ -19,9 +21,19 @@ type StaticApp struct {
 	App        *apiv1.App
 	Props      *types.StaticAppProperties
 	DeployOpts *types.StaticAppDeployOptions
+	Opts       *StaticAppOptions
 
-	domains []string
+	domains        []string
+	secrets        map[string]string
+	previewSecrets map[string]string
+	ready          bool
+	message        string
+	pendingDomains []string
+	projectName    string
+	branch         string
+	previewDomain  string
 
+	KVNamespaces    map[string]*cf.KVNamespace
 	PagesProject    *cf.PagesProject
 	PagesFiles      *cf.PagesFiles
 	PagesDeployment *cf.PagesDeployment
@@ -38,31 +50,134 @@ func NewStaticApp(plan *apiv1.AppPlan, domains []string) (*StaticApp, error) {
 		return nil, err
 	}
 
+	cfOpts, err := NewStaticAppOptions(plan.State.App.Properties.AsMap())
+	if err != nil {
+		return nil, err
+	}
+
 	return &StaticApp{
 		App:        plan.State.App,
 		Props:      opts,
 		DeployOpts: deployOpts,
+		Opts:       cfOpts,
 		domains:    domains,
+		ready:      true,
 	}, nil
 }
 
-func (o *StaticApp) process(ctx context.Context, pctx *config.PluginContext, r *registry.Registry) error {
+func (o *StaticApp) envVars(vars map[string]interface{}) (production, preview map[string]fields.Field, err error) {
+	eval := fields.NewFieldVarEvaluator(vars)
+	production = make(map[string]fields.Field)
+	preview = make(map[string]fields.Field)
+
+	for k, v := range o.App.Env {
+		production[k], err = eval.Expand(v)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		preview[k] = production[k]
+	}
+
+	for k, v := range o.Opts.PreviewEnv {
+		preview[k], err = eval.Expand(v)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return production, preview, nil
+}
+
+// isPreview returns true if app is deployed as preview deployment of a project shared with production environment.
+func (o *StaticApp) isPreview(e env.Enver) bool {
+	return o.Opts.ProductionEnv != "" && o.Opts.ProductionEnv != e.Env()
+}
+
+func (o *StaticApp) registerProject(pctx *config.PluginContext, r *registry.Registry, vars map[string]interface{}) error {
 	cli := pctx.CloudflareClient()
-	pagesProject := cf.ID(pctx.Env(), o.App.Id)
+	domains := make([]fields.Field, 0, len(o.domains))
 
-	domains := make([]fields.Field, len(o.domains))
+	for _, d := range o.domains {
+		if d != "" {
+			domains = append(domains, fields.String(d))
+		}
+	}
 
-	for i, d := range o.domains {
-		domains[i] = fields.String(d)
+	productionEnv, previewEnv, err := o.envVars(vars)
+	if err != nil {
+		return err
 	}
 
+	productionBindings, previewBindings := o.bindings()
+
 	o.PagesProject = &cf.PagesProject{
-		Name:      fields.String(pagesProject),
-		AccountID: fields.String(cli.AccountID),
-		Domains:   fields.Array(domains),
+		Name:               fields.String(o.projectName),
+		AccountID:          fields.String(cli.AccountID),
+		Domains:            fields.Array(domains),
+		ProductionEnvVars:  fields.Map(productionEnv),
+		PreviewEnvVars:     fields.Map(previewEnv),
+		ProductionSecrets:  fields.Map(secretHashes(o.secrets)),
+		PreviewSecrets:     fields.Map(secretHashes(o.previewSecrets)),
+		ProductionBindings: fields.Map(productionBindings),
+		PreviewBindings:    fields.Map(previewBindings),
+
+		ProductionSecretValues: o.secrets,
+		PreviewSecretValues:    o.previewSecrets,
+	}
+
+	if o.Opts.ProductionBranch != "" {
+		o.PagesProject.ProductionBranch = fields.String(o.Opts.ProductionBranch)
+	}
+
+	if o.Opts.CompatibilityDate != "" {
+		o.PagesProject.CompatibilityDate = fields.String(o.Opts.CompatibilityDate)
+	}
+
+	if b := o.Opts.Build; b != nil {
+		o.PagesProject.BuildCommand = fields.String(b.Command)
+		o.PagesProject.BuildOutputDir = fields.String(b.OutputDir)
+		o.PagesProject.BuildRootDir = fields.String(b.RootDir)
+	}
+
+	_, err = r.RegisterAppResource(o.App, "pages_project", o.PagesProject)
+
+	return err
+}
+
+// usePreviewProject uses project managed by production environment, which is never modified by preview environments.
+func (o *StaticApp) usePreviewProject(ctx context.Context, pctx *config.PluginContext) error {
+	proj, err := pctx.WranglerCloudflareClient().PagesProject(ctx, o.projectName)
+	if err != nil {
+		return fmt.Errorf("%s app '%s' has to be deployed in '%s' environment first: %w", o.App.Type, o.App.Name, o.Opts.ProductionEnv, err)
+	}
+
+	o.branch = cf.PagesBranch(pctx.Env())
+	o.previewDomain = fmt.Sprintf("%s.%s", o.branch, proj.SubDomain)
+	o.PagesProject = &cf.PagesProject{
+		Name:      fields.String(o.projectName),
+		AccountID: fields.String(pctx.CloudflareClient().AccountID),
+	}
+
+	return nil
+}
+
+func (o *StaticApp) process(ctx context.Context, pctx *config.PluginContext, r *registry.Registry, vars map[string]interface{}) error {
+	var err error
+
+	o.projectName = cf.ID(pctx.Env(), o.App.Id)
+
+	// Project shared between environments is named after production one.
+	if o.Opts.ProductionEnv != "" {
+		o.projectName = cf.SharedID(pctx.Env(), o.App.Id)
+	}
+
+	if o.isPreview(pctx.Env()) {
+		err = o.usePreviewProject(ctx, pctx)
+	} else {
+		err = o.registerProject(pctx, r, vars)
 	}
 
-	_, err := r.RegisterAppResource(o.App, "pages_project", o.PagesProject)
 	if err != nil {
 		return err
 	}
@@ -74,8 +189,18 @@ func (o *StaticApp) process(ctx context.Context, pctx *config.PluginContext, r *
 		return fmt.Errorf("%s app '%s' build dir '%s' does not exist", o.App.Type, o.App.Name, buildDir)
 	}
 
-	hashesInterface, err := pctx.FuncCache(fmt.Sprintf("PagesFiles:%s:hash", pagesProject), func() (interface{}, error) {
-		return cf.PagesFindFiles(buildPath, o.DeployOpts.Patterns)
+	hashesInterface, err := pctx.FuncCache(fmt.Sprintf("PagesFiles:%s:hash", o.projectName), func() (interface{}, error) {
+		cache := cf.LoadPagesHashCache(filepath.Join(pctx.Env().ProjectDir(), ".outblocks", "cloudflare", fmt.Sprintf("pages_hashes_%s.json", o.projectName)))
+
+		hashes, err := cf.PagesFindFiles(ctx, buildPath, o.DeployOpts.Patterns, cache)
+		if err != nil {
+			return nil, err
+		}
+
+		// Cache is only an optimization, failing to save it should not fail deployment.
+		_ = cache.Save()
+
+		return hashes, nil
 	})
 	if err != nil {
 		return err
@@ -83,7 +208,7 @@ func (o *StaticApp) process(ctx context.Context, pctx *config.PluginContext, r *
 
 	hashes := hashesInterface.(map[string]*cf.PagesFileInfo)
 	if len(hashes) > cf.CloudflarePagesMaxFileCount {
-		return fmt.Errorf("CloudFlare Pages only supports files up %d files, ensure you have specified build output directory correctly", cf.CloudflarePagesMaxSize)
+		return fmt.Errorf("CloudFlare Pages only supports up to %d files, ensure you have specified build output directory correctly", cf.CloudflarePagesMaxFileCount)
 	}
 
 	hashesList := make([]string, 0, len(hashes))
@@ -92,7 +217,7 @@ func (o *StaticApp) process(ctx context.Context, pctx *config.PluginContext, r *
 	}
 
 	o.PagesFiles = &cf.PagesFiles{
-		Name:        pagesProject,
+		Name:        o.projectName,
 		ProjectName: o.PagesProject.Name,
 		Hashes:      hashes,
 		HashesList:  hashesList,
@@ -103,11 +228,35 @@ func (o *StaticApp) process(ctx context.Context, pctx *config.PluginContext, r *
 		return err
 	}
 
+	headers, err := o.pagesHeaders(buildPath)
+	if err != nil {
+		return err
+	}
+
+	redirects, err := o.pagesRedirects(buildPath)
+	if err != nil {
+		return err
+	}
+
+	functions, err := o.pagesFunctions(filepath.Join(pctx.Env().ProjectDir(), o.App.Dir), buildPath)
+	if err != nil {
+		return err
+	}
+
 	o.PagesDeployment = &cf.PagesDeployment{
-		Name:        pagesProject,
-		ProjectName: o.PagesProject.Name,
-		AccountID:   o.PagesProject.AccountID,
-		Manifest:    o.PagesFiles.Manifest.Input(),
+		Name:            o.projectName,
+		ProjectName:     o.PagesProject.Name,
+		AccountID:       o.PagesProject.AccountID,
+		Manifest:        o.PagesFiles.Manifest.Input(),
+		Headers:         fields.String(headers),
+		Redirects:       fields.String(redirects),
+		WorkerHash:      fields.String(functions.workerHash),
+		Routes:          fields.String(functions.routes),
+		WorkerPath:      functions.workerPath,
+		FunctionsDir:    functions.functionsDir,
+		FunctionsOutDir: filepath.Join(pctx.Env().ProjectDir(), ".outblocks", "cloudflare", fmt.Sprintf("pages_functions_%s", o.projectName)),
+		Branch:          fields.String(o.branch),
+		PinnedID:        fields.String(o.Opts.DeploymentID),
 	}
 
 	_, err = r.RegisterAppResource(o.App, "pages_deployment", o.PagesDeployment)
@@ -119,7 +268,8 @@ func (o *StaticApp) process(ctx context.Context, pctx *config.PluginContext, r *
 }
 
 func (o *StaticApp) DNSRecord() *apiv1.DNSRecord {
-	if o.App.Url == "" {
+	// Custom domains belong to production deployment.
+	if o.App.Url == "" || o.branch != "" {
 		return nil
 	}
 
@@ -132,19 +282,157 @@ func (o *StaticApp) DNSRecord() *apiv1.DNSRecord {
 
 func (o *StaticApp) AppState() *apiv1.AppState {
 	cloudURL := ""
+	url := o.App.Url
 
-	if o.PagesProject != nil {
+	switch {
+	case o.branch != "":
+		cloudURL = fmt.Sprintf("https://%s", o.previewDomain)
+		url = cloudURL
+	case o.PagesProject != nil:
 		cloudURL = fmt.Sprintf("https://%s", o.PagesProject.InternalDomain.Current())
 	}
 
 	return &apiv1.AppState{
 		App: o.App,
 		Deployment: &apiv1.DeploymentState{
-			Ready: true,
+			Ready:   o.ready,
+			Message: o.message,
 		},
 		Dns: &apiv1.DNSState{
-			Url:      o.App.Url,
+			Url:      url,
 			CloudUrl: cloudURL,
 		},
 	}
 }
+
+const (
+	pagesDeploymentTimeout     = 5 * time.Minute
+	pagesDomainsDefaultTimeout = 2 * time.Minute
+	pagesPollInterval          = 5 * time.Second
+)
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// waitForDeployment polls deployment until its last stage finishes.
+func (o *StaticApp) waitForDeployment(ctx context.Context, wranglerCli *config.WranglerCloudflareAPI) error {
+	project := o.projectName
+	id := o.PagesDeployment.LiveID.Current()
+	deadline := time.Now().Add(pagesDeploymentTimeout)
+
+	if o.branch != "" {
+		id = o.PagesDeployment.ID.Current()
+	}
+
+	if id == "" {
+		return nil
+	}
+
+	for {
+		d, err := wranglerCli.PagesDeployment(ctx, project, id)
+		if err != nil {
+			return fmt.Errorf("error fetching pages deployment: %w", err)
+		}
+
+		stage := d.LatestStage
+
+		switch {
+		case stage.Status == "failure" || stage.Status == "canceled":
+			o.ready = false
+			o.message = fmt.Sprintf("deployment %s failed at '%s' stage", id, stage.Name)
+
+			return nil
+		case stage.Name == "deploy" && stage.Status == "success":
+			return nil
+		case time.Now().After(deadline):
+			o.ready = false
+			o.message = fmt.Sprintf("deployment %s still in progress, '%s' stage is %s", id, stage.Name, stage.Status)
+
+			return nil
+		}
+
+		err = sleepContext(ctx, pagesPollInterval)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// checkDomains lists custom domains that are not active yet along with validation records they require.
+func (o *StaticApp) checkDomains(ctx context.Context, wranglerCli *config.WranglerCloudflareAPI) error {
+	domains, err := wranglerCli.PagesDomains(ctx, o.projectName)
+	if err != nil {
+		return fmt.Errorf("error fetching pages domains: %w", err)
+	}
+
+	o.pendingDomains = nil
+
+	for _, d := range domains {
+		if d.Status == "active" {
+			continue
+		}
+
+		msg := fmt.Sprintf("domain '%s' is %s", d.Name, d.Status)
+
+		if v := d.ValidationData; v.TXTName != "" {
+			msg += fmt.Sprintf(", add TXT record '%s' with value '%s'", v.TXTName, v.TXTValue)
+		}
+
+		if v := d.ValidationData; v.ErrorMessage != "" {
+			msg += fmt.Sprintf(" (%s)", v.ErrorMessage)
+		}
+
+		o.pendingDomains = append(o.pendingDomains, msg)
+	}
+
+	return nil
+}
+
+func (o *StaticApp) domainsTimeout() time.Duration {
+	if o.Opts.DomainsTimeout == nil {
+		return pagesDomainsDefaultTimeout
+	}
+
+	return time.Duration(*o.Opts.DomainsTimeout) * time.Second
+}
+
+// waitForDomains polls custom domains until all of them are active or timeout passes.
+// Domains still pending after timeout are only reported and do not fail deploy, as DNS records of new domains
+// are created after apps are deployed, so they cannot become active before that.
+func (o *StaticApp) waitForDomains(ctx context.Context, wranglerCli *config.WranglerCloudflareAPI) error {
+	deadline := time.Now().Add(o.domainsTimeout())
+
+	for {
+		err := o.checkDomains(ctx, wranglerCli)
+		if err != nil || len(o.pendingDomains) == 0 || time.Now().Add(pagesPollInterval).After(deadline) {
+			return err
+		}
+
+		err = sleepContext(ctx, pagesPollInterval)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// WaitReady waits for deployment to become active, updating app state accordingly, and for custom domains to become active.
+func (o *StaticApp) WaitReady(ctx context.Context, pctx *config.PluginContext) error {
+	if o.PagesProject == nil || o.PagesDeployment == nil {
+		return nil
+	}
+
+	wranglerCli := pctx.WranglerCloudflareClient()
+
+	err := o.waitForDeployment(ctx, wranglerCli)
+	if err != nil || !o.ready || o.branch != "" {
+		return err
+	}
+
+	return o.waitForDomains(ctx, wranglerCli)
+}
@@ -0,0 +1,34 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+)
+
+func TestWildcardOriginHostnames(t *testing.T) {
+	p := NewPlugin()
+	p.accountZones["example.com"] = "zone1"
+	p.accountZones["sub.example.org"] = "zone2"
+
+	tests := []struct {
+		domains []string
+		want    []string
+	}{
+		{[]string{"www.example.com", "api.example.com"}, []string{"*.example.com", "example.com"}},
+		{[]string{"*.example.com", "example.com"}, []string{"*.example.com", "example.com"}},
+		{[]string{"a.sub.example.org", "www.example.com"}, []string{"*.example.com", "*.sub.example.org", "example.com", "sub.example.org"}},
+		// Nested subdomains get wildcard of their parent instead of being dropped.
+		{[]string{"api.staging.example.com"}, []string{"*.staging.example.com"}},
+		{[]string{"*.app.staging.example.com", "Web.Staging.example.com."}, []string{"*.app.staging.example.com", "*.staging.example.com"}},
+		{[]string{"a.b.sub.example.org", "c.b.sub.example.org"}, []string{"*.b.sub.example.org"}},
+	}
+
+	for _, tt := range tests {
+		got := p.wildcardOriginHostnames(&apiv1.DomainInfo{Domains: tt.domains})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wildcardOriginHostnames(%v) = %v, want %v", tt.domains, got, tt.want)
+		}
+	}
+}
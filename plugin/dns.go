@@ -13,17 +13,6 @@ import (
 	"github.com/outblocks/outblocks-plugin-go/types"
 )
 
-func getDomainZoneName(rec string) string {
-	split := strings.Split(rec, ".")
-	l := len(split)
-
-	if l < 2 {
-		return ""
-	}
-
-	return fmt.Sprintf("%s.%s", split[l-2], split[l-1])
-}
-
 func getHostname(rec string) string {
 	split := strings.SplitN(rec, "://", 2)
 	if len(split) == 2 {
@@ -38,31 +27,164 @@ func getHostname(rec string) string {
 	return rec
 }
 
-func (p *Plugin) registerDNSRecords(reg *registry.Registry, domains []*apiv1.DomainInfo, records []*apiv1.DNSRecord) error {
+func (p *Plugin) zoneIDForZone(zone string) string {
+	zoneID := p.zoneMap[zone]
+	if zoneID == "" {
+		zoneID = p.accountZones[zone]
+	}
+
+	return zoneID
+}
+
+func (p *Plugin) registerDNSRecord(reg *registry.Registry, zone string, o *cf.DNSRecord) error {
+	key := o.Key()
+
+	_, err := reg.RegisterPluginResource(zone, key, o)
+	if err != nil {
+		return err
+	}
+
+	p.PluginContext().AddWantedDNSRecord(o.ZoneID.Wanted(), key)
+
+	// Fields are initialized only once resource is registered.
+	return o.Validate()
+}
+
+func customDNSRecords(domains []*apiv1.DomainInfo) ([]*DNSRecordOptions, error) {
+	var ret []*DNSRecordOptions
+
+	for _, di := range domains {
+		if di.DnsPlugin != "cloudflare" {
+			continue
+		}
+
+		opts, err := NewDomainOptions(di)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rec := range opts.DNSRecords {
+			if rec.Name == "" && len(di.Domains) > 0 {
+				rec.Name = di.Domains[0]
+			}
+
+			if rec.Adopt == nil {
+				rec.Adopt = &opts.Adopt
+			}
+
+			rec.Type = strings.ToUpper(rec.Type)
+			ret = append(ret, rec)
+		}
+	}
+
+	return ret, nil
+}
+
+func (p *Plugin) registerDNSRecords(ctx context.Context, reg *registry.Registry, domains []*apiv1.DomainInfo, records []*apiv1.DNSRecord) error {
 	matcher := types.NewDomainInfoMatcher(domains)
 
+	custom, err := customDNSRecords(domains)
+	if err != nil {
+		return err
+	}
+
+	recs := make([]string, 0, len(records)+len(custom))
+
 	for _, rec := range records {
-		di := matcher.Match(rec.Record)
-		proxy := di != nil && di.Properties.AsMap()["cloudflare_proxy"] == true && strings.Count(rec.Record, ".") <= 2
+		recs = append(recs, rec.Record)
+	}
 
-		zone := getDomainZoneName(rec.Record)
+	for _, rec := range custom {
+		recs = append(recs, rec.Name)
+	}
+
+	err = p.resolveZones(ctx, recs)
+	if err != nil {
+		return err
+	}
+
+	owner := cf.DNSOwnerID(p.env)
+
+	for _, rec := range records {
+		zone := p.getDomainZoneName(rec.Record)
 		if zone == "" {
 			continue
 		}
 
-		zoneID := p.zoneMap[zone]
+		typ := rec.Type.String()[len("TYPE_"):]
+		di := matcher.Match(rec.Record)
+		proxy := di != nil && di.Properties.AsMap()["cloudflare_proxy"] == true && subdomainLevel(rec.Record, zone) <= 1 && cf.DNSProxiableTypes[typ]
+		adopt := false
 
-		o := cf.DNSRecord{
-			ZoneID:  fields.String(zoneID),
+		if di != nil {
+			opts, err := NewDomainOptions(di)
+			if err != nil {
+				return err
+			}
+
+			adopt = opts.Adopt
+		}
+
+		o := &cf.DNSRecord{
+			ZoneID:  fields.String(p.zoneIDForZone(zone)),
 			Name:    fields.String(rec.Record),
-			Type:    fields.String(rec.Type.String()[len("TYPE_"):]),
+			Type:    fields.String(typ),
 			Value:   fields.String(rec.Value),
 			Proxied: fields.Bool(proxy),
+			Owner:   fields.String(owner),
+			Adopt:   adopt,
 		}
 
 		rec.Created = true
 
-		_, err := reg.RegisterPluginResource(zone, fmt.Sprintf("%s::%s", rec.Record, rec.Type), &o)
+		err = p.registerDNSRecord(reg, zone, o)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, rec := range custom {
+		zone := p.getDomainZoneName(rec.Name)
+		if zone == "" {
+			return fmt.Errorf("dns record '%s': cannot determine zone", rec.Name)
+		}
+
+		ttl := rec.TTL
+		if ttl == 0 {
+			ttl = cf.DNSRecordAutoTTL
+		}
+
+		o := &cf.DNSRecord{
+			ZoneID:  fields.String(p.zoneIDForZone(zone)),
+			Name:    fields.String(rec.Name),
+			Type:    fields.String(rec.Type),
+			Value:   fields.String(rec.Value),
+			Proxied: fields.Bool(rec.Proxied),
+			TTL:     fields.Int(ttl),
+			Owner:   fields.String(owner),
+			Adopt:   *rec.Adopt,
+		}
+
+		if rec.Priority != nil {
+			o.Priority = fields.Int(*rec.Priority)
+		}
+
+		// Key of structured records is based on their data, so it has to be set even if empty.
+		// Their content is computed by cloudflare, so value is tracked as canonical form of data instead.
+		if _, ok := cf.DNSDataTypes[rec.Type]; ok {
+			data := make(map[string]fields.Field, len(rec.Data))
+			dataValues := make(map[string]interface{}, len(rec.Data))
+
+			for k, v := range rec.Data {
+				data[k] = fields.String(fmt.Sprint(v))
+				dataValues[k] = fmt.Sprint(v)
+			}
+
+			o.Data = fields.Map(data)
+			o.Value = fields.String(cf.DNSRecordDataKey(dataValues))
+		}
+
+		err = p.registerDNSRecord(reg, zone, o)
 		if err != nil {
 			return err
 		}
@@ -92,7 +214,7 @@ func (p *Plugin) PlanDNS(ctx context.Context, reg *registry.Registry, r *apiv1.P
 	}
 
 	// Register DNS Records.
-	err = p.registerDNSRecords(reg, r.Domains, records)
+	err = p.registerDNSRecords(ctx, reg, r.Domains, records)
 	if err != nil {
 		return nil, err
 	}
@@ -103,6 +225,11 @@ func (p *Plugin) PlanDNS(ctx context.Context, reg *registry.Registry, r *apiv1.P
 		return nil, err
 	}
 
+	err = pctx.CheckUnownedDNSRecords()
+	if err != nil {
+		return nil, err
+	}
+
 	data, err := reg.Dump()
 	if err != nil {
 		return nil, err
@@ -130,7 +257,7 @@ func (p *Plugin) ApplyDNS(r *apiv1.ApplyDNSRequest, reg *registry.Registry, stre
 	}
 
 	// Register DNS Records.
-	err = p.registerDNSRecords(reg, r.Domains, records)
+	err = p.registerDNSRecords(ctx, reg, r.Domains, records)
 	if err != nil {
 		return err
 	}
@@ -141,6 +268,11 @@ func (p *Plugin) ApplyDNS(r *apiv1.ApplyDNSRequest, reg *registry.Registry, stre
 		return err
 	}
 
+	err = pctx.CheckUnownedDNSRecords()
+	if err != nil {
+		return err
+	}
+
 	err = reg.Apply(ctx, pctx, diff, plugin_go.DefaultRegistryApplyDNSCallback(stream))
 
 	data, saveErr := reg.Dump()
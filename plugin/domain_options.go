@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/mitchellh/mapstructure"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+)
+
+type DNSRecordOptions struct {
+	Name     string                 `mapstructure:"name"`
+	Type     string                 `mapstructure:"type"`
+	Value    string                 `mapstructure:"value"`
+	TTL      int                    `mapstructure:"ttl"`
+	Priority *int                   `mapstructure:"priority"`
+	Proxied  bool                   `mapstructure:"proxied"`
+	Data     map[string]interface{} `mapstructure:"data"`
+	Adopt    *bool                  `mapstructure:"adopt"`
+}
+
+type HSTSOptions struct {
+	Enabled           *bool `mapstructure:"enabled"`
+	MaxAge            int   `mapstructure:"max_age"`
+	IncludeSubdomains bool  `mapstructure:"include_subdomains"`
+	Preload           bool  `mapstructure:"preload"`
+	NoSniff           bool  `mapstructure:"nosniff"`
+}
+
+// ZoneOptions are zone-level settings, unset ones are left untouched.
+type ZoneOptions struct {
+	SSL            string       `mapstructure:"ssl"`
+	MinTLSVersion  string       `mapstructure:"min_tls_version"`
+	AlwaysUseHTTPS *bool        `mapstructure:"always_use_https"`
+	HSTS           *HSTSOptions `mapstructure:"hsts"`
+	HTTP3          *bool        `mapstructure:"http3"`
+	Brotli         *bool        `mapstructure:"brotli"`
+}
+
+// OriginPullsOptions configure Authenticated Origin Pulls. Cloudflare certificate is used unless own one is provided.
+type OriginPullsOptions struct {
+	PerHostname bool   `mapstructure:"per_hostname"`
+	Certificate string `mapstructure:"certificate"`
+	PrivateKey  string `mapstructure:"private_key"`
+	// CA is bundle that origin should use to verify client certificate, defaults to the certificate itself.
+	CA string `mapstructure:"ca"`
+}
+
+// DomainOptions are cloudflare specific properties of a domain.
+type DomainOptions struct {
+	DNSRecords []*DNSRecordOptions `mapstructure:"cloudflare_dns_records"`
+	Adopt      bool                `mapstructure:"cloudflare_adopt"`
+	Zone       *ZoneOptions        `mapstructure:"cloudflare_zone"`
+
+	// OriginRenewDays is how many days before expiry Origin CA certificate gets renewed.
+	OriginRenewDays int `mapstructure:"cloudflare_origin_renew_days"`
+	// OriginType is Origin CA certificate request type, either origin-rsa (default) or origin-ecc.
+	OriginType string `mapstructure:"cloudflare_origin_type"`
+	// OriginWildcard makes Origin CA certificate cover whole zone with a wildcard, so it can be shared between domains.
+	OriginWildcard bool `mapstructure:"cloudflare_origin_wildcard"`
+
+	OriginPulls *OriginPullsOptions `mapstructure:"cloudflare_origin_pulls"`
+}
+
+func NewDomainOptions(domain *apiv1.DomainInfo) (*DomainOptions, error) {
+	o := &DomainOptions{}
+
+	err := mapstructure.WeakDecode(domain.Properties.AsMap(), o)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cloudflare properties of domain '%s': %w", domain.Domains, err)
+	}
+
+	return o, nil
+}
+
+func mergeStringOption(zone, name string, dst *string, src string) error {
+	if src == "" {
+		return nil
+	}
+
+	if *dst != "" && *dst != src {
+		return fmt.Errorf("conflicting '%s' setting for zone '%s': '%s' and '%s'", name, zone, *dst, src)
+	}
+
+	*dst = src
+
+	return nil
+}
+
+func mergeBoolOption(zone, name string, dst **bool, src *bool) error {
+	if src == nil {
+		return nil
+	}
+
+	if *dst != nil && **dst != *src {
+		return fmt.Errorf("conflicting '%s' setting for zone '%s'", name, zone)
+	}
+
+	*dst = src
+
+	return nil
+}
+
+// Merge merges zone settings declared on another domain of the same zone.
+func (o *ZoneOptions) Merge(zone string, other *ZoneOptions) error {
+	if other == nil {
+		return nil
+	}
+
+	if other.HSTS != nil {
+		if o.HSTS != nil && !reflect.DeepEqual(o.HSTS, other.HSTS) {
+			return fmt.Errorf("conflicting 'hsts' setting for zone '%s'", zone)
+		}
+
+		o.HSTS = other.HSTS
+	}
+
+	for _, err := range []error{
+		mergeStringOption(zone, "ssl", &o.SSL, other.SSL),
+		mergeStringOption(zone, "min_tls_version", &o.MinTLSVersion, other.MinTLSVersion),
+		mergeBoolOption(zone, "always_use_https", &o.AlwaysUseHTTPS, other.AlwaysUseHTTPS),
+		mergeBoolOption(zone, "http3", &o.HTTP3, other.HTTP3),
+		mergeBoolOption(zone, "brotli", &o.Brotli, other.Brotli),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/outblocks/cli-plugin-cloudflare/cf"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+)
+
+var kvBindingNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// appKVNamespaces returns binding names of KV namespaces of app and whether existing ones should be adopted.
+// Preview static apps use bindings of production environment.
+func (p *Plugin) appKVNamespaces(app *apiv1.App) ([]string, bool, error) {
+	switch app.Type {
+	case AppTypeStatic:
+		opts, err := NewStaticAppOptions(app.Properties.AsMap())
+		if err != nil {
+			return nil, false, err
+		}
+
+		if opts.ProductionEnv != "" && opts.ProductionEnv != p.env.Env() {
+			return nil, false, nil
+		}
+
+		return opts.KVNamespaces, opts.KVAdopt, nil
+
+	case AppTypeFunction:
+		opts, err := NewFunctionAppOptions(app.Properties.AsMap())
+		if err != nil {
+			return nil, false, err
+		}
+
+		return opts.KVNamespaces, opts.KVAdopt, nil
+	}
+
+	return nil, false, nil
+}
+
+// registerKVNamespaces registers KV namespaces of all apps before apps are processed, so that any app can reference them.
+// Namespace IDs are returned as vars, available as ${cloudflare_kv.<app name>.<binding>}.
+func (p *Plugin) registerKVNamespaces(reg *registry.Registry, appPlans []*apiv1.AppPlan) (map[string]interface{}, error) {
+	vars := make(map[string]interface{})
+
+	for _, plan := range appPlans {
+		if plan.Skip {
+			continue
+		}
+
+		app := plan.State.App
+
+		names, adopt, err := p.appKVNamespaces(app)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(names) == 0 {
+			continue
+		}
+
+		namespaces := make(map[string]*cf.KVNamespace, len(names))
+		appVars := make(map[string]interface{}, len(names))
+
+		for _, name := range names {
+			if !kvBindingNameRegex.MatchString(name) {
+				return nil, fmt.Errorf("%s app '%s' has invalid kv namespace binding name '%s'", app.Type, app.Name, name)
+			}
+
+			ns := &cf.KVNamespace{
+				AccountID: fields.String(p.cli.AccountID),
+				Title:     fields.String(cf.ID(p.env, fmt.Sprintf("%s_%s", app.Id, name))),
+				Adopt:     adopt,
+			}
+
+			_, err = reg.RegisterAppResource(app, "kv_namespace:"+name, ns)
+			if err != nil {
+				return nil, err
+			}
+
+			namespaces[name] = ns
+			appVars[name] = ns.ID.Input()
+		}
+
+		p.kvNamespaces[app.Id] = namespaces
+		vars[app.Name] = appVars
+	}
+
+	return vars, nil
+}
@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/outblocks/cli-plugin-cloudflare/cf"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	originPullsCAStateKey = "origin_pulls_ca"
+	originPullsCATimeout  = 30 * time.Second
+)
+
+// cloudflareOriginPullsCA returns CA of cloudflare origin pulls certificate. It is downloaded only once and cached in plugin state.
+func (p *Plugin) cloudflareOriginPullsCA(ctx context.Context, state *apiv1.PluginState) (string, error) {
+	if ca := state.Other[originPullsCAStateKey]; len(ca) != 0 {
+		return string(ca), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cf.OriginPullsCAURL, http.NoBody)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := (&http.Client{Timeout: originPullsCATimeout}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error downloading cloudflare origin pulls ca: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error downloading cloudflare origin pulls ca: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error downloading cloudflare origin pulls ca: %w", err)
+	}
+
+	if state.Other == nil {
+		state.Other = make(map[string][]byte)
+	}
+
+	state.Other[originPullsCAStateKey] = data
+
+	return string(data), nil
+}
+
+func newOriginPulls(zone string, zoneID fields.StringInputField, mode string, hostnames []string, opts *OriginPullsOptions) *cf.OriginPulls {
+	h := make([]fields.Field, len(hostnames))
+
+	for i, v := range hostnames {
+		h[i] = fields.String(v)
+	}
+
+	return &cf.OriginPulls{
+		ZoneID:      zoneID,
+		Zone:        fields.String(zone),
+		Mode:        fields.String(mode),
+		Hostnames:   fields.Array(h),
+		Certificate: fields.String(opts.Certificate),
+		PrivateKey:  opts.PrivateKey,
+	}
+}
+
+type zoneOriginPulls struct {
+	opts      *OriginPullsOptions
+	hostnames []string
+}
+
+func (z *zoneOriginPulls) merge(zone string, opts *OriginPullsOptions) error {
+	if z.opts.Certificate != opts.Certificate || z.opts.PrivateKey != opts.PrivateKey {
+		return fmt.Errorf("conflicting authenticated origin pulls certificate for zone '%s'", zone)
+	}
+
+	return nil
+}
+
+// registerOriginPulls registers Authenticated Origin Pulls of domains and passes CA bundle to verify cloudflare requests with
+// as 'cloudflare_origin_pulls_ca' domain property. Per-hostname pulls of a zone share a single uploaded certificate.
+func (p *Plugin) registerOriginPulls(ctx context.Context, reg *registry.Registry, state *apiv1.PluginState, domains []*apiv1.DomainInfo) error {
+	zonePulls := make(map[string]*zoneOriginPulls)
+	hostnamePulls := make(map[string]*zoneOriginPulls)
+
+	for _, d := range domains {
+		opts, err := NewDomainOptions(d)
+		if err != nil {
+			return err
+		}
+
+		pulls := opts.OriginPulls
+		if pulls == nil {
+			continue
+		}
+
+		zone := p.getDomainZoneName(d.Domains[0])
+
+		if _, ok := p.zones[zone]; !ok {
+			continue
+		}
+
+		if (pulls.Certificate == "") != (pulls.PrivateKey == "") {
+			return fmt.Errorf("authenticated origin pulls of domain '%s' require both certificate and private_key to be set", d.Domains)
+		}
+
+		ca := pulls.CA
+
+		switch {
+		case pulls.Certificate == "" && pulls.PerHostname:
+			return fmt.Errorf("per-hostname authenticated origin pulls of domain '%s' require certificate and private_key to be set", d.Domains)
+		case pulls.Certificate == "":
+			ca, err = p.cloudflareOriginPullsCA(ctx, state)
+			if err != nil {
+				return err
+			}
+		case ca == "":
+			ca = pulls.Certificate
+		}
+
+		m := zonePulls
+		if pulls.PerHostname {
+			m = hostnamePulls
+		}
+
+		if prev, ok := m[zone]; ok {
+			err = prev.merge(zone, pulls)
+			if err != nil {
+				return err
+			}
+		} else {
+			m[zone] = &zoneOriginPulls{opts: pulls}
+		}
+
+		if pulls.PerHostname {
+			m[zone].hostnames = append(m[zone].hostnames, d.Domains...)
+		}
+
+		if d.Properties.GetFields() == nil {
+			d.Properties, _ = structpb.NewStruct(nil)
+		}
+
+		d.Properties.Fields["cloudflare_origin_pulls_ca"] = structpb.NewStringValue(ca)
+	}
+
+	for mode, m := range map[string]map[string]*zoneOriginPulls{
+		cf.OriginPullsModeZone:     zonePulls,
+		cf.OriginPullsModeHostname: hostnamePulls,
+	} {
+		for zone, pulls := range m {
+			// Hostnames are sorted to match their order when read.
+			sort.Strings(pulls.hostnames)
+
+			_, err := reg.RegisterPluginResource(zone, "origin_pulls_"+mode, newOriginPulls(zone, p.zones[zone].ID.Input(), mode, pulls.hostnames, pulls.opts))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
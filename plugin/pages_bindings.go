@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/outblocks/cli-plugin-cloudflare/cf"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+)
+
+func (b *PagesBindingsOptions) addFields(m map[string]fields.Field) {
+	if b == nil {
+		return
+	}
+
+	for typ, bindings := range map[string]map[string]string{
+		cf.PagesBindingKV:            b.KV,
+		cf.PagesBindingR2:            b.R2,
+		cf.PagesBindingD1:            b.D1,
+		cf.PagesBindingDurableObject: b.DurableObjects,
+	} {
+		for name, id := range bindings {
+			m[cf.PagesBindingKey(typ, name)] = fields.String(id)
+		}
+	}
+}
+
+func (o *StaticApp) bindings() (production, preview map[string]fields.Field) {
+	production = make(map[string]fields.Field)
+	preview = make(map[string]fields.Field)
+
+	for name, ns := range o.KVNamespaces {
+		production[cf.PagesBindingKey(cf.PagesBindingKV, name)] = ns.ID.Input()
+		preview[cf.PagesBindingKey(cf.PagesBindingKV, name)] = ns.ID.Input()
+	}
+
+	o.Opts.Bindings.addFields(production)
+	o.Opts.Bindings.addFields(preview)
+	o.Opts.PreviewBindings.addFields(preview)
+
+	return production, preview
+}
+
+func hostSecrets(ctx context.Context, hostCli apiv1.HostServiceClient, secrets, ret map[string]string) error {
+	for name, key := range secrets {
+		res, err := hostCli.HostGetSecret(ctx, &apiv1.HostGetSecretRequest{
+			Key: key,
+		})
+		if err != nil {
+			return fmt.Errorf("error getting secret '%s': %w", key, err)
+		}
+
+		if res.Value == "" {
+			return fmt.Errorf("secret '%s' used by env var '%s' is not set", key, name)
+		}
+
+		ret[name] = res.Value
+	}
+
+	return nil
+}
+
+// secretHashes returns fields with hashes of secrets, which is what is tracked in state instead of their values.
+func secretHashes(secrets map[string]string) map[string]fields.Field {
+	ret := make(map[string]fields.Field, len(secrets))
+
+	for name, v := range secrets {
+		ret[name] = fields.String(cf.PagesSecretHash(name, v))
+	}
+
+	return ret
+}
+
+// resolveSecrets gets values of secret env vars from outblocks secret store.
+func (o *StaticApp) resolveSecrets(ctx context.Context, hostCli apiv1.HostServiceClient) error {
+	for _, secrets := range []map[string]string{o.Opts.Secrets, o.Opts.PreviewSecrets} {
+		for name := range secrets {
+			_, inEnv := o.App.Env[name]
+			_, inPreviewEnv := o.Opts.PreviewEnv[name]
+
+			if inEnv || inPreviewEnv {
+				return fmt.Errorf("%s app '%s' env var '%s' cannot be both a plain env var and a secret", o.App.Type, o.App.Name, name)
+			}
+		}
+	}
+
+	o.secrets = make(map[string]string)
+	o.previewSecrets = make(map[string]string)
+
+	err := hostSecrets(ctx, hostCli, o.Opts.Secrets, o.secrets)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range o.secrets {
+		o.previewSecrets[k] = v
+	}
+
+	return hostSecrets(ctx, hostCli, o.Opts.PreviewSecrets, o.previewSecrets)
+}
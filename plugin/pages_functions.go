@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/outblocks/cli-plugin-cloudflare/cf"
+	plugin_util "github.com/outblocks/outblocks-plugin-go/util"
+	"github.com/zeebo/blake3"
+)
+
+type pagesFunctions struct {
+	workerPath   string
+	functionsDir string
+	workerHash   string
+	routes       string
+}
+
+// pagesFunctions detects Pages Functions worker and its routes in build dir.
+// When there is none, functions directory of app is used, which is compiled with wrangler only when deployment is created.
+func (o *StaticApp) pagesFunctions(appPath, buildPath string) (*pagesFunctions, error) {
+	ret := &pagesFunctions{}
+	workerFile := filepath.Join(buildPath, cf.PagesWorkerFile)
+	routesFile := filepath.Join(buildPath, cf.PagesRoutesFile)
+
+	if plugin_util.FileExists(workerFile) {
+		data, err := os.ReadFile(workerFile)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := blake3.Sum256(data)
+		ret.workerPath = workerFile
+		ret.workerHash = hex.EncodeToString(sum[:])[:32]
+	} else {
+		functionsDir := filepath.Join(appPath, cf.PagesFunctionsDir)
+
+		if _, ok := plugin_util.CheckDir(functionsDir); !ok {
+			return ret, nil
+		}
+
+		hash, err := cf.PagesFunctionsHash(functionsDir)
+		if err != nil {
+			return nil, err
+		}
+
+		ret.functionsDir = functionsDir
+		ret.workerHash = hash
+	}
+
+	// Routes in build dir take precedence over ones generated from functions directory.
+	if !plugin_util.FileExists(routesFile) {
+		return ret, nil
+	}
+
+	data, err := os.ReadFile(routesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cf.ValidatePagesRoutes(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s app '%s' has invalid %s: %w", o.App.Type, o.App.Name, cf.PagesRoutesFile, err)
+	}
+
+	ret.routes = string(data)
+
+	return ret, nil
+}
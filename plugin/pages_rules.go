@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/outblocks/cli-plugin-cloudflare/cf"
+)
+
+const (
+	PagesRoutingSPA = "spa"
+
+	pagesSPAFallbackRule       = "/* /index.html 200"
+	pagesDefaultRedirectStatus = 302
+)
+
+func readPagesRuleFile(buildPath, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(buildPath, name))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func joinPagesRules(rules []string) string {
+	if len(rules) == 0 {
+		return ""
+	}
+
+	return strings.Join(rules, "\n") + "\n"
+}
+
+// pagesRedirects returns _redirects file with rules from build dir followed by generated ones, as first matching rule wins.
+func (o *StaticApp) pagesRedirects(buildPath string) (string, error) {
+	existing, err := readPagesRuleFile(buildPath, cf.PagesRedirectsFile)
+	if err != nil {
+		return "", err
+	}
+
+	var rules []string
+
+	if existing != "" {
+		rules = append(rules, existing)
+	}
+
+	for _, r := range o.Opts.Redirects {
+		status := r.Status
+		if status == 0 {
+			status = pagesDefaultRedirectStatus
+		}
+
+		rules = append(rules, fmt.Sprintf("%s %s %d", r.From, r.To, status))
+	}
+
+	switch o.Opts.Routing {
+	case "":
+	case PagesRoutingSPA:
+		rules = append(rules, pagesSPAFallbackRule)
+	default:
+		return "", fmt.Errorf("%s app '%s' has invalid cloudflare routing '%s', supported values: %s", o.App.Type, o.App.Name, o.Opts.Routing, PagesRoutingSPA)
+	}
+
+	content := joinPagesRules(rules)
+
+	err = cf.ValidatePagesRedirects(content)
+	if err != nil {
+		return "", fmt.Errorf("%s app '%s' has invalid %s: %w", o.App.Type, o.App.Name, cf.PagesRedirectsFile, err)
+	}
+
+	return content, nil
+}
+
+// pagesHeaders returns _headers file with rules from build dir followed by generated ones.
+func (o *StaticApp) pagesHeaders(buildPath string) (string, error) {
+	existing, err := readPagesRuleFile(buildPath, cf.PagesHeadersFile)
+	if err != nil {
+		return "", err
+	}
+
+	var rules []string
+
+	if existing != "" {
+		rules = append(rules, existing)
+	}
+
+	paths := make([]string, 0, len(o.Opts.Headers))
+
+	for path := range o.Opts.Headers {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		headers := o.Opts.Headers[path]
+		names := make([]string, 0, len(headers))
+
+		for name := range headers {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		rules = append(rules, path)
+
+		for _, name := range names {
+			rules = append(rules, fmt.Sprintf("  %s: %s", name, headers[name]))
+		}
+	}
+
+	content := joinPagesRules(rules)
+
+	err = cf.ValidatePagesHeaders(content)
+	if err != nil {
+		return "", fmt.Errorf("%s app '%s' has invalid %s: %w", o.App.Type, o.App.Name, cf.PagesHeadersFile, err)
+	}
+
+	return content, nil
+}
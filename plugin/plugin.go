@@ -18,22 +18,28 @@ type Plugin struct {
 	cli         *cloudflare.API
 	wranglerCli *config.WranglerCloudflareAPI
 
-	settings         config.Settings
-	zoneMap          map[string]string
-	originCerts      map[*cf.OriginCertificate]*apiv1.DomainInfo
-	nonOriginDomains []*apiv1.DomainInfo
+	settings     config.Settings
+	zoneMap      map[string]string
+	accountZones map[string]string
+	zones        map[string]*cf.Zone
+	zoneOptions  map[string]*ZoneOptions
+	originCerts  map[*cf.OriginCertificate][]*apiv1.DomainInfo
 
 	staticApps    map[string]*StaticApp
 	functionApps  map[string]*FunctionApp
+	kvNamespaces  map[string]map[string]*cf.KVNamespace
 	pluginContext *config.PluginContext
 }
 
 func NewPlugin() *Plugin {
 	return &Plugin{
-		originCerts:  make(map[*cf.OriginCertificate]*apiv1.DomainInfo),
+		originCerts:  make(map[*cf.OriginCertificate][]*apiv1.DomainInfo),
 		zoneMap:      map[string]string{},
+		accountZones: map[string]string{},
+		zones:        make(map[string]*cf.Zone),
 		staticApps:   make(map[string]*StaticApp),
 		functionApps: make(map[string]*FunctionApp),
+		kvNamespaces: make(map[string]map[string]*cf.KVNamespace),
 	}
 }
 
@@ -0,0 +1,308 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/outblocks/cli-plugin-cloudflare/cf"
+	apiv1 "github.com/outblocks/outblocks-plugin-go/gen/api/v1"
+	"github.com/outblocks/outblocks-plugin-go/registry"
+	"github.com/outblocks/outblocks-plugin-go/registry/fields"
+	"golang.org/x/net/publicsuffix"
+)
+
+func normalizeDomain(rec string) string {
+	return strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(rec), "."), "*.")
+}
+
+// publicSuffixZoneName returns registrable domain (eTLD+1) of record based on public suffix list.
+func publicSuffixZoneName(rec string) string {
+	zone, err := publicsuffix.EffectiveTLDPlusOne(normalizeDomain(rec))
+	if err != nil {
+		return ""
+	}
+
+	return zone
+}
+
+// zoneCandidates returns all possible zone names of record, starting from the most specific one up to the apex.
+func zoneCandidates(rec string) []string {
+	rec = normalizeDomain(rec)
+	apex := publicSuffixZoneName(rec)
+
+	if apex == "" {
+		return nil
+	}
+
+	var ret []string
+
+	for {
+		ret = append(ret, rec)
+
+		if rec == apex {
+			return ret
+		}
+
+		idx := strings.Index(rec, ".")
+		if idx == -1 {
+			return ret
+		}
+
+		rec = rec[idx+1:]
+	}
+}
+
+// subdomainLevel returns how many labels record has on top of zone, e.g. "a.b.example.com" has level 2 in "example.com" zone.
+func subdomainLevel(rec, zone string) int {
+	rec = strings.TrimSuffix(strings.ToLower(rec), ".")
+
+	if rec == zone {
+		return 0
+	}
+
+	return strings.Count(strings.TrimSuffix(rec, "."+zone), ".") + 1
+}
+
+// resolveZones looks up which zone candidates of records exist in cloudflare account.
+func (p *Plugin) resolveZones(ctx context.Context, recs []string) error {
+	var lookup []string
+
+	seen := make(map[string]struct{})
+
+	for _, rec := range recs {
+		for _, c := range zoneCandidates(rec) {
+			if _, ok := p.accountZones[c]; ok {
+				continue
+			}
+
+			if _, ok := seen[c]; ok {
+				continue
+			}
+
+			seen[c] = struct{}{}
+			lookup = append(lookup, c)
+		}
+	}
+
+	if len(lookup) == 0 {
+		return nil
+	}
+
+	zones, err := p.cli.ListZones(ctx, lookup...)
+	if err != nil {
+		return fmt.Errorf("cannot lookup cloudflare zones: %w", err)
+	}
+
+	// Misses are not cached as zones can be created later on during the same run.
+	for _, z := range zones { //nolint: gocritic
+		p.accountZones[strings.ToLower(z.Name)] = z.ID
+	}
+
+	return nil
+}
+
+// getDomainZoneName returns the most specific zone of record that exists in cloudflare account,
+// falling back to registrable domain when no such zone exists.
+func (p *Plugin) getDomainZoneName(rec string) string {
+	candidates := zoneCandidates(rec)
+
+	for _, c := range candidates {
+		if p.accountZones[c] != "" {
+			return c
+		}
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	return candidates[len(candidates)-1]
+}
+
+const defaultHSTSMaxAge = 15552000
+
+func newZoneResource(name string, opts *ZoneOptions) *cf.Zone {
+	o := &cf.Zone{
+		Name: fields.String(name),
+	}
+
+	if opts.MinTLSVersion != "" {
+		o.MinTLSVersion = fields.String(opts.MinTLSVersion)
+	}
+
+	if opts.AlwaysUseHTTPS != nil {
+		o.AlwaysUseHTTPS = fields.Bool(*opts.AlwaysUseHTTPS)
+	}
+
+	if opts.HTTP3 != nil {
+		o.HTTP3 = fields.Bool(*opts.HTTP3)
+	}
+
+	if opts.Brotli != nil {
+		o.Brotli = fields.Bool(*opts.Brotli)
+	}
+
+	if opts.HSTS != nil {
+		maxAge := opts.HSTS.MaxAge
+		if maxAge == 0 {
+			maxAge = defaultHSTSMaxAge
+		}
+
+		o.HSTSEnabled = fields.Bool(opts.HSTS.Enabled == nil || *opts.HSTS.Enabled)
+		o.HSTSMaxAge = fields.Int(maxAge)
+		o.HSTSIncludeSubdomains = fields.Bool(opts.HSTS.IncludeSubdomains)
+		o.HSTSPreload = fields.Bool(opts.HSTS.Preload)
+		o.HSTSNoSniff = fields.Bool(opts.HSTS.NoSniff)
+	}
+
+	return o
+}
+
+// registerZones registers zones of all domains using cloudflare DNS. Zones of other domains are only looked up.
+func (p *Plugin) registerZones(ctx context.Context, reg *registry.Registry, state *apiv1.PluginState, domains []*apiv1.DomainInfo) error {
+	// Zone IDs used to be cached in plugin state, they are now tracked in registry.
+	delete(state.Other, "zone_id_map")
+
+	var recs []string
+
+	for _, domainInfo := range domains {
+		recs = append(recs, domainInfo.Domains...)
+	}
+
+	err := p.resolveZones(ctx, recs)
+	if err != nil {
+		return err
+	}
+
+	zoneOpts := make(map[string]*ZoneOptions)
+
+	for _, domainInfo := range domains {
+		if domainInfo.DnsPlugin != "cloudflare" {
+			for _, d := range domainInfo.Domains {
+				zone := p.getDomainZoneName(d)
+
+				if id := p.accountZones[zone]; id != "" {
+					p.zoneMap[zone] = id
+				}
+			}
+
+			continue
+		}
+
+		opts, err := NewDomainOptions(domainInfo)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range domainInfo.Domains {
+			zone := p.getDomainZoneName(d)
+			if zone == "" {
+				continue
+			}
+
+			if _, ok := zoneOpts[zone]; !ok {
+				zoneOpts[zone] = &ZoneOptions{}
+			}
+
+			err = zoneOpts[zone].Merge(zone, opts.Zone)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	p.zoneOptions = zoneOpts
+	zones := make([]string, 0, len(zoneOpts))
+
+	for zone := range zoneOpts {
+		zones = append(zones, zone)
+	}
+
+	sort.Strings(zones)
+
+	for _, zone := range zones {
+		o := newZoneResource(zone, zoneOpts[zone])
+
+		_, err = reg.RegisterPluginResource(zone, "zone", o)
+		if err != nil {
+			return err
+		}
+
+		p.zones[zone] = o
+	}
+
+	return nil
+}
+
+// processZones updates zone IDs of registered zones and reports zones pending nameserver change.
+// Zones to be created and their nameservers are shown in plan through name of zone resource.
+func (p *Plugin) processZones(report bool) {
+	names := make([]string, 0, len(p.zones))
+
+	for name := range p.zones {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		z := p.zones[name]
+
+		id := z.ID.Current()
+		if id == "" {
+			continue
+		}
+
+		p.zoneMap[name] = id
+		p.accountZones[name] = id
+
+		if report && z.Status.Current() == cf.ZoneStatusPending {
+			p.log.Infof("Cloudflare zone '%s' is pending activation, set nameservers of domain to: %s\n", name, z.NameServers.Current())
+		}
+	}
+}
+
+// registerZoneSSLSettings registers SSL mode of zones. Zones with Origin CA certificates default to strict mode,
+// others are left untouched unless set explicitly. Mode set in domain options always takes precedence.
+func (p *Plugin) registerZoneSSLSettings(reg *registry.Registry) error {
+	originZones := make(map[string]bool)
+
+	for _, domains := range p.originCerts {
+		for _, domain := range domains {
+			for _, d := range domain.Domains {
+				originZones[p.getDomainZoneName(d)] = true
+			}
+		}
+	}
+
+	for zone, z := range p.zones {
+		mode := p.zoneOptions[zone].SSL
+
+		if mode == "" && originZones[zone] {
+			mode = cf.ZoneSSLModeStrict
+		}
+
+		if mode == "" {
+			continue
+		}
+
+		switch mode {
+		case cf.ZoneSSLModeOff, cf.ZoneSSLModeFlexible, cf.ZoneSSLModeFull, cf.ZoneSSLModeStrict:
+		default:
+			return fmt.Errorf("invalid ssl mode '%s' for zone '%s', supported values: off, flexible, full, strict", mode, zone)
+		}
+
+		_, err := reg.RegisterPluginResource(zone, "zone_ssl", &cf.ZoneSSLSetting{
+			Zone:   fields.String(zone),
+			ZoneID: z.ID.Input(),
+			Mode:   fields.String(mode),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDomainZoneName(t *testing.T) {
+	p := NewPlugin()
+	p.accountZones["example.com"] = "zone1"
+	p.accountZones["sub.example.org"] = "zone2"
+
+	tests := []struct {
+		rec  string
+		want string
+	}{
+		{"www.example.com", "example.com"},
+		{"a.sub.example.org", "sub.example.org"},
+		{"sub.example.org", "sub.example.org"},
+		{"other.example.org", "example.org"},
+		{"www.example.net", "example.net"},
+		{"localhost", ""},
+	}
+
+	for _, tt := range tests {
+		if got := p.getDomainZoneName(tt.rec); got != tt.want {
+			t.Errorf("getDomainZoneName(%q) = %q, want %q", tt.rec, got, tt.want)
+		}
+	}
+}
+
+func TestZoneCandidates(t *testing.T) {
+	tests := []struct {
+		rec  string
+		want []string
+	}{
+		{"example.com", []string{"example.com"}},
+		{"www.example.com", []string{"www.example.com", "example.com"}},
+		{"A.B.Example.com.", []string{"a.b.example.com", "b.example.com", "example.com"}},
+		{"*.app.example.com", []string{"app.example.com", "example.com"}},
+		{"app.example.co.uk", []string{"app.example.co.uk", "example.co.uk"}},
+		{"app.example.pages.dev", []string{"app.example.pages.dev", "example.pages.dev"}},
+		{"co.uk", nil},
+		{"localhost", nil},
+	}
+
+	for _, tt := range tests {
+		if got := zoneCandidates(tt.rec); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("zoneCandidates(%q) = %v, want %v", tt.rec, got, tt.want)
+		}
+	}
+}
+
+func TestSubdomainLevel(t *testing.T) {
+	tests := []struct {
+		rec, zone string
+		want      int
+	}{
+		{"example.com", "example.com", 0},
+		{"Example.com.", "example.com", 0},
+		{"www.example.com", "example.com", 1},
+		{"*.example.com", "example.com", 1},
+		{"a.b.example.com", "example.com", 2},
+		{"*.app.example.com", "example.com", 2},
+		{"a.b.example.com", "b.example.com", 1},
+	}
+
+	for _, tt := range tests {
+		if got := subdomainLevel(tt.rec, tt.zone); got != tt.want {
+			t.Errorf("subdomainLevel(%q, %q) = %d, want %d", tt.rec, tt.zone, got, tt.want)
+		}
+	}
+}